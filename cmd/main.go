@@ -58,6 +58,10 @@ func main() {
 
 	ic := controller.NewIngressController(ctrlConf, scClient, kubeClient)
 
+	if ctrlConf.WebhookBindAddress != "" {
+		go ic.RunWebhook()
+	}
+
 	hostname, err := os.Hostname()
 	if err != nil {
 		klog.Fatalf("unable to get hostname: %v", err)
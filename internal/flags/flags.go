@@ -2,9 +2,13 @@ package flags
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/loadbalancer"
 
 	"github.com/serverscom/serverscom-ingress-controller/internal/config"
 
@@ -36,6 +40,33 @@ func ParseFlags() (*controller.Configuration, error) {
 
 		certManagerPrefix = flags.String("cert-manager-prefix", "sc-certmgr-cert-id-",
 			`Cert manager prefix is used in ingress tls secret name to determine should we lookup for cert from API or not. Default 'sc-certmgr-cert-id-'.`)
+
+		defaultBackendService = flags.String("default-backend-service", "",
+			`Service used as a fallback for requests which don't match any rule, in 'namespace/name[:port]' format. Ingress defaultBackend takes precedence.`)
+
+		reclaimPolicy = flags.String("load-balancer-reclaim-policy", annotations.ReclaimPolicyDelete,
+			`Default reclaim policy of load balancers, 'Delete' or 'Retain'. Retained load balancers are kept in portal when Ingress is removed. Overridden by servers.com/load-balancer-reclaim-policy annotation.`)
+
+		nameTemplate = flags.String("load-balancer-name-template", "",
+			`Go template of load balancer names, e.g. '{{.ClusterID}}-{{.Namespace}}-{{.Name}}-{{.UIDHash}}'. Available variables: ClusterID, Namespace, Name, Class, UIDHash, the template must use UIDHash. Names are 'ingress-a<uid>' if empty. Overridden by servers.com/load-balancer-name annotation.`)
+
+		sslRedirect = flags.Bool("ssl-redirect", true,
+			`If set, HTTP requests to hosts with TLS are redirected to HTTPS, otherwise both HTTP and HTTPS are served. Enabled by default, so hosts with TLS don't serve plain HTTP. Overridden by servers.com/ssl-redirect annotation.`)
+
+		refuseConflictingRules = flags.Bool("refuse-conflicting-rules", false,
+			`If set, rules which host and path are claimed by an older Ingress aren't synced. Conflicts are reported with HostConflict events anyway.`)
+
+		healthChecksFromProbes = flags.Bool("health-checks-from-probes", false,
+			`If set, upstream health checks default to HTTP readiness probes of service pods. Requires pods list and watch permissions. Health check annotations take precedence.`)
+
+		webhookBindAddress = flags.String("webhook-bind-address", "",
+			`Address for the validating admission webhook HTTPS server, e.g. ':8443'. Webhook is disabled if empty.`)
+
+		webhookCertFile = flags.String("webhook-cert-file", "",
+			`Path to the TLS certificate file of the admission webhook server.`)
+
+		webhookKeyFile = flags.String("webhook-key-file", "",
+			`Path to the TLS private key file of the admission webhook server.`)
 	)
 
 	flags.AddGoFlagSet(flag.CommandLine)
@@ -50,6 +81,37 @@ func ParseFlags() (*controller.Configuration, error) {
 		ResyncPeriod:      *resyncPeriod,
 		IngressClass:      *ingressClass,
 		CertManagerPrefix: *certManagerPrefix,
+		ReclaimPolicy:     *reclaimPolicy,
+		SSLRedirect:       *sslRedirect,
+
+		RefuseConflictingRules: *refuseConflictingRules,
+		HealthChecksFromProbes: *healthChecksFromProbes,
+
+		WebhookBindAddress: *webhookBindAddress,
+		WebhookCertFile:    *webhookCertFile,
+		WebhookKeyFile:     *webhookKeyFile,
+	}
+
+	if *defaultBackendService != "" {
+		defaultBackend, err := store.ParseDefaultBackend(*defaultBackendService)
+		if err != nil {
+			return nil, err
+		}
+		conf.DefaultBackend = defaultBackend
+	}
+
+	if _, err := annotations.ParseOneOf(conf.ReclaimPolicy, annotations.ReclaimPolicies); err != nil {
+		return nil, fmt.Errorf("invalid --load-balancer-reclaim-policy: %v", err)
+	}
+
+	tmpl, err := loadbalancer.ParseNameTemplate(*nameTemplate)
+	if err != nil {
+		return nil, err
+	}
+	conf.NameTemplate = tmpl
+
+	if conf.WebhookBindAddress != "" && (conf.WebhookCertFile == "" || conf.WebhookKeyFile == "") {
+		return nil, fmt.Errorf("--webhook-cert-file and --webhook-key-file are required when --webhook-bind-address is set")
 	}
 
 	k8sopts.BindLeaderElectionFlags(conf.LeaderElectionCfg, flags)
@@ -33,6 +33,9 @@ func TestParseFlagsDefaults(t *testing.T) {
 		"--watch-namespace", "default",
 		"--ingress-class", "nginx",
 		"--sync-period", "30s",
+		"--refuse-conflicting-rules",
+		"--ssl-redirect=false",
+		"--health-checks-from-probes",
 	}
 
 	conf, err := ParseFlags()
@@ -41,4 +44,140 @@ func TestParseFlagsDefaults(t *testing.T) {
 	g.Expect(conf.Namespace).To(Equal("default"))
 	g.Expect(conf.IngressClass).To(Equal("nginx"))
 	g.Expect(conf.ResyncPeriod).To(Equal(30 * time.Second))
+	g.Expect(conf.RefuseConflictingRules).To(BeTrue())
+	g.Expect(conf.SSLRedirect).To(BeFalse())
+	g.Expect(conf.HealthChecksFromProbes).To(BeTrue())
+
+	// hosts with TLS don't serve plain HTTP by default
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{"cmd"}
+
+	conf, err = ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.SSLRedirect).To(BeTrue())
+}
+
+func TestParseFlagsWebhook(t *testing.T) {
+	g := NewWithT(t)
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{
+		"cmd",
+		"--webhook-bind-address", ":8443",
+	}
+
+	_, err := ParseFlags()
+	g.Expect(err).To(HaveOccurred())
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{
+		"cmd",
+		"--webhook-bind-address", ":8443",
+		"--webhook-cert-file", "/certs/tls.crt",
+		"--webhook-key-file", "/certs/tls.key",
+	}
+
+	conf, err := ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.WebhookBindAddress).To(Equal(":8443"))
+	g.Expect(conf.WebhookCertFile).To(Equal("/certs/tls.crt"))
+	g.Expect(conf.WebhookKeyFile).To(Equal("/certs/tls.key"))
+}
+
+func TestParseFlagsDefaultBackend(t *testing.T) {
+	g := NewWithT(t)
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{
+		"cmd",
+		"--default-backend-service", "default-backend",
+	}
+
+	_, err := ParseFlags()
+	g.Expect(err).To(HaveOccurred())
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{
+		"cmd",
+		"--default-backend-service", "kube-system/default-backend:8080",
+	}
+
+	conf, err := ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.DefaultBackend).NotTo(BeNil())
+	g.Expect(conf.DefaultBackend.Namespace).To(Equal("kube-system"))
+	g.Expect(conf.DefaultBackend.Name).To(Equal("default-backend"))
+	g.Expect(conf.DefaultBackend.Port.Number).To(Equal(int32(8080)))
+}
+
+func TestParseFlagsReclaimPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd"}
+
+	conf, err := ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.ReclaimPolicy).To(Equal("Delete"))
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{
+		"cmd",
+		"--load-balancer-reclaim-policy", "Keep",
+	}
+
+	_, err = ParseFlags()
+	g.Expect(err).To(HaveOccurred())
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{
+		"cmd",
+		"--load-balancer-reclaim-policy", "Retain",
+	}
+
+	conf, err = ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.ReclaimPolicy).To(Equal("Retain"))
+}
+
+func TestParseFlagsNameTemplate(t *testing.T) {
+	g := NewWithT(t)
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd"}
+
+	conf, err := ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.NameTemplate).To(BeNil())
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{
+		"cmd",
+		"--load-balancer-name-template", "{{.Class}}",
+	}
+
+	_, err = ParseFlags()
+	g.Expect(err).To(HaveOccurred())
+
+	ResetForTesting(func() { t.Fatal("Parsing failed") })
+	os.Args = []string{
+		"cmd",
+		"--load-balancer-name-template", "{{.Namespace}}-{{.Name}}-{{.UIDHash}}",
+	}
+
+	conf, err = ParseFlags()
+	g.Expect(err).To(BeNil())
+	g.Expect(conf.NameTemplate).NotTo(BeNil())
 }
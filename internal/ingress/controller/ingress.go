@@ -9,6 +9,7 @@ import (
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/loadbalancer"
 	syncer "github.com/serverscom/serverscom-ingress-controller/internal/service/sync"
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/tls"
+	"github.com/serverscom/serverscom-ingress-controller/internal/webhook"
 
 	"sync"
 
@@ -19,6 +20,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/component-base/config"
@@ -37,9 +39,14 @@ type IngressController struct {
 	queue    workqueue.RateLimitingInterface
 	store    store.Storer
 	service  *service.Service
+	webhook  *webhook.Server
 	stopCh   chan struct{}
 	stopLock sync.Mutex
 	shutdown bool
+
+	// webhookStore is a read-only store of admission webhook, it's synced on every replica
+	webhookStore  *store.Store
+	webhookStopCh chan struct{}
 }
 
 // Configuration contains all the settings required by an Ingress controller
@@ -51,6 +58,17 @@ type Configuration struct {
 	ResyncPeriod      time.Duration
 	IngressClass      string
 	CertManagerPrefix string
+	DefaultBackend    *store.DefaultBackend
+	ReclaimPolicy     string
+	NameTemplate      *loadbalancer.NameTemplate
+	SSLRedirect       bool
+
+	RefuseConflictingRules bool
+	HealthChecksFromProbes bool
+
+	WebhookBindAddress string
+	WebhookCertFile    string
+	WebhookKeyFile     string
 }
 
 // NewIngressController creates a new ingress controller
@@ -75,9 +93,11 @@ func NewIngressController(config *Configuration, scClient *serverscom.Client, ku
 		config.IngressClass,
 		ic.recorder,
 		ic.queue,
+		config.DefaultBackend,
+		config.HealthChecksFromProbes,
 	)
 	tlsManager := tls.NewManager(scClient, ic.store)
-	lbManager := loadbalancer.NewManager(scClient, ic.store)
+	lbManager := loadbalancer.NewManager(scClient, ic.store, ic.recorder, config.ReclaimPolicy, config.NameTemplate, config.SSLRedirect)
 	ic.service = service.New(
 		kubeClient,
 		tlsManager,
@@ -85,10 +105,21 @@ func NewIngressController(config *Configuration, scClient *serverscom.Client, ku
 		ic.store,
 		syncer.New(tlsManager, lbManager, ic.store, clockwork.NewRealClock()),
 		ic.recorder,
+		ic.queue,
 		config.IngressClass,
 		config.CertManagerPrefix,
 		config.Namespace,
+		config.RefuseConflictingRules,
 	)
+	ic.webhookStore = store.NewReadOnly(
+		config.Namespace,
+		config.ResyncPeriod,
+		config.KubeClient,
+		config.IngressClass,
+		config.DefaultBackend,
+	)
+	ic.webhookStopCh = make(chan struct{})
+	ic.webhook = webhook.New(ic.webhookStore, config.IngressClass, config.CertManagerPrefix, config.SSLRedirect)
 
 	return ic
 }
@@ -106,13 +137,31 @@ func (ic *IngressController) Run(stopCh chan struct{}) {
 	<-stopCh
 }
 
+// RunWebhook runs read-only informers and admission webhook server until controller is stopped.
+// Webhook runs on every replica regardless of leader election and serves requests after informers are synced.
+func (ic *IngressController) RunWebhook() {
+	go ic.webhookStore.Run(ic.webhookStopCh)
+	if !cache.WaitForCacheSync(ic.webhookStopCh, ic.webhookStore.HasSynced) {
+		klog.Errorf("admission webhook server isn't started: informers aren't synced")
+		return
+	}
+
+	err := ic.webhook.Run(ic.conf.WebhookBindAddress, ic.conf.WebhookCertFile, ic.conf.WebhookKeyFile, ic.webhookStopCh)
+	if err != nil {
+		klog.Fatalf("admission webhook server failed: %v", err)
+	}
+}
+
 // Stop gracefully stops controller
 func (ic *IngressController) Stop() {
 	ic.stopLock.Lock()
 	defer ic.stopLock.Unlock()
 
 	if !ic.shutdown {
-		close(ic.stopCh)
+		if ic.stopCh != nil {
+			close(ic.stopCh)
+		}
+		close(ic.webhookStopCh)
 
 		ic.queue.ShutDown()
 
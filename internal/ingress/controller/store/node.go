@@ -1,6 +1,8 @@
 package store
 
 import (
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/cache"
 )
@@ -18,18 +20,36 @@ type NodeLister struct {
 func (l *NodeLister) NodesIpList() []string {
 	var ips []string
 	for _, obj := range l.List() {
-		node := obj.(*corev1.Node)
-		// Skip master nodes
-		if _, ok := node.Labels[MasterNodeAnnotationKey]; ok {
-			continue
-		}
-		for _, address := range node.Status.Addresses {
-			if address.Type == corev1.NodeInternalIP {
-				ips = append(ips, address.Address)
-				break
-			}
+		if ip, ok := getNodeUpstreamIP(obj.(*corev1.Node)); ok {
+			ips = append(ips, ip)
 		}
 	}
 
 	return ips
 }
+
+// NodesWeights returns upstream weights of nodes by their ips, 0 for drained nodes
+func (l *NodeLister) NodesWeights() map[string]int {
+	weights := make(map[string]int)
+	for _, obj := range l.List() {
+		node := obj.(*corev1.Node)
+		if ip, ok := getNodeUpstreamIP(node); ok {
+			weights[ip] = annotations.GetUpstreamWeight(node.Annotations)
+		}
+	}
+
+	return weights
+}
+
+// getNodeUpstreamIP returns internal ip of node which is used in upstreams, master nodes are skipped
+func getNodeUpstreamIP(node *corev1.Node) (string, bool) {
+	if _, ok := node.Labels[MasterNodeAnnotationKey]; ok {
+		return "", false
+	}
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return address.Address, true
+		}
+	}
+	return "", false
+}
@@ -3,13 +3,18 @@ package store
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"time"
 
 	"github.com/serverscom/serverscom-ingress-controller/internal/ingress"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 
 	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
-	"k8s.io/apimachinery/pkg/util/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
@@ -20,6 +25,17 @@ import (
 
 //go:generate mockgen --destination ../../../mocks/store.go --package=mocks --source store.go
 
+const (
+	// ByServiceIndex indexes ingresses by namespaced keys of backend services
+	ByServiceIndex = "byService"
+	// BySecretIndex indexes ingresses by namespaced keys of tls secrets
+	BySecretIndex = "bySecret"
+	// ByHostPathIndex indexes ingresses of controller class by host and path of their rules
+	ByHostPathIndex = "byHostPath"
+	// BySelectorIndex indexes services by namespaced label pairs of their selectors
+	BySelectorIndex = "bySelector"
+)
+
 // NotExistsError is returned when an object does not exist in a local store.
 type NotExistsError string
 
@@ -37,7 +53,11 @@ type Storer interface {
 	ListIngress() []*networkv1.Ingress
 	GetService(key string) (*corev1.Service, error)
 	GetNodesIpList() []string
+	GetNodeWeights() map[string]int
+	GetServicePods(svc *corev1.Service) []*corev1.Pod
 	GetIngressHostsInfo(ingress *networkv1.Ingress) (map[string]HostInfo, error)
+	GetHostConflicts(ingress *networkv1.Ingress) []HostConflict
+	GetHostPathIngresses(ingress *networkv1.Ingress) []*networkv1.Ingress
 }
 
 // Store represents cache store, implements Storer
@@ -47,6 +67,9 @@ type Store struct {
 
 	// listers contains the cache.Store interfaces used in the ingress controller
 	listers *Lister
+
+	// defaultBackend is a controller wide default backend, optional
+	defaultBackend *DefaultBackend
 }
 
 // GetSecret returns the Secret matching key.
@@ -74,9 +97,110 @@ func (s *Store) GetNodesIpList() []string {
 	return s.listers.Node.NodesIpList()
 }
 
+// GetNodeWeights returns upstream weights of nodes by their ips
+func (s *Store) GetNodeWeights() map[string]int {
+	return s.listers.Node.NodesWeights()
+}
+
+// GetServicePods returns pods selected by service ordered by name.
+// Returns nil if pods aren't watched.
+func (s *Store) GetServicePods(svc *corev1.Service) []*corev1.Pod {
+	if s.informers.Pod == nil || len(svc.Spec.Selector) == 0 {
+		return nil
+	}
+	objs, err := s.informers.Pod.GetIndexer().ByIndex(cache.NamespaceIndex, svc.Namespace)
+	if err != nil {
+		klog.Errorf("getting pods of service %s/%s failed: %v", svc.Namespace, svc.Name, err)
+		return nil
+	}
+	selector := labels.SelectorFromSet(svc.Spec.Selector)
+	var pods []*corev1.Pod
+	for _, obj := range objs {
+		pod := obj.(*corev1.Pod)
+		if selector.Matches(labels.Set(pod.Labels)) {
+			pods = append(pods, pod)
+		}
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+	return pods
+}
+
 // GetIngressServiceInfo returns ingress services info.
 func (s *Store) GetIngressHostsInfo(ingress *networkv1.Ingress) (map[string]HostInfo, error) {
-	return getIngressHostsInfo(ingress, s)
+	return getIngressHostsInfo(ingress, s, s.defaultBackend)
+}
+
+// GetHostConflicts returns hosts and paths of ingress which are claimed by other ingresses of controller class.
+// The oldest ingress wins, ingress which isn't created yet is the newest.
+// Canary ingresses share hosts and paths with other ingresses, so they don't conflict.
+func (s *Store) GetHostConflicts(ingress *networkv1.Ingress) []HostConflict {
+	if annotations.IsCanary(ingress.Annotations) {
+		return nil
+	}
+	var conflicts []HostConflict
+	indexer := s.informers.Ingress.GetIndexer()
+	for _, hp := range getIngressHostPaths(ingress) {
+		objs, err := indexer.ByIndex(ByHostPathIndex, hp.Host+hp.Path)
+		if err != nil {
+			klog.Errorf("getting ingresses by host and path failed: %v", err)
+			continue
+		}
+
+		var owner *networkv1.Ingress
+		for _, obj := range objs {
+			other := obj.(*networkv1.Ingress)
+			if other.Namespace == ingress.Namespace && other.Name == ingress.Name {
+				continue
+			}
+			if annotations.IsCanary(other.Annotations) {
+				continue
+			}
+			if owner == nil || IsOlderIngress(other, owner) {
+				owner = other
+			}
+		}
+		if owner != nil && IsOlderIngress(owner, ingress) {
+			conflicts = append(conflicts, HostConflict{
+				Host:  hp.Host,
+				Path:  hp.Path,
+				Owner: owner.Namespace + "/" + owner.Name,
+			})
+		}
+	}
+	return conflicts
+}
+
+// GetHostPathIngresses returns other ingresses of controller class which share hosts and paths with ingress,
+// ordered from the oldest one
+func (s *Store) GetHostPathIngresses(ingress *networkv1.Ingress) []*networkv1.Ingress {
+	var res []*networkv1.Ingress
+	seen := make(map[string]struct{})
+	indexer := s.informers.Ingress.GetIndexer()
+	for _, hp := range getIngressHostPaths(ingress) {
+		objs, err := indexer.ByIndex(ByHostPathIndex, hp.Host+hp.Path)
+		if err != nil {
+			klog.Errorf("getting ingresses by host and path failed: %v", err)
+			continue
+		}
+		for _, obj := range objs {
+			other := obj.(*networkv1.Ingress)
+			key := other.Namespace + "/" + other.Name
+			if other.Namespace == ingress.Namespace && other.Name == ingress.Name {
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			res = append(res, other)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return IsOlderIngress(res[i], res[j])
+	})
+	return res
 }
 
 type Informer struct {
@@ -84,6 +208,8 @@ type Informer struct {
 	Service cache.SharedIndexInformer
 	Secret  cache.SharedIndexInformer
 	Node    cache.SharedIndexInformer
+	// Pod is set only if pods are watched
+	Pod cache.SharedIndexInformer
 }
 
 type Lister struct {
@@ -98,21 +224,22 @@ func (i *Informer) Run(stopCh chan struct{}) {
 	go i.Secret.Run(stopCh)
 	go i.Service.Run(stopCh)
 	go i.Node.Run(stopCh)
+	synced := []cache.InformerSynced{i.Service.HasSynced, i.Secret.HasSynced, i.Node.HasSynced}
+	if i.Pod != nil {
+		go i.Pod.Run(stopCh)
+		synced = append(synced, i.Pod.HasSynced)
+	}
 
 	// wait for all involved caches to be synced, before processing items from the queue is started
-	if !cache.WaitForCacheSync(stopCh,
-		i.Service.HasSynced,
-		i.Secret.HasSynced,
-		i.Node.HasSynced,
-	) {
-		runtime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
+	if !cache.WaitForCacheSync(stopCh, synced...) {
+		utilruntime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 	}
 
 	go i.Ingress.Run(stopCh)
 	if !cache.WaitForCacheSync(stopCh,
 		i.Ingress.HasSynced,
 	) {
-		runtime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
+		utilruntime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 	}
 }
 
@@ -121,69 +248,41 @@ func (s *Store) Run(stopCh chan struct{}) {
 	s.informers.Run(stopCh)
 }
 
+// HasSynced returns true if all informers of store have synced
+func (s *Store) HasSynced() bool {
+	synced := s.informers.Ingress.HasSynced() && s.informers.Service.HasSynced() &&
+		s.informers.Secret.HasSynced() && s.informers.Node.HasSynced()
+	if s.informers.Pod != nil {
+		synced = synced && s.informers.Pod.HasSynced()
+	}
+	return synced
+}
+
+// NewReadOnly creates a new store without event handlers,
+// e.g. for the admission webhook which runs on every replica.
+func NewReadOnly(
+	namespace string,
+	resyncPeriod time.Duration,
+	client kubernetes.Interface,
+	ingressClass string,
+	defaultBackend *DefaultBackend,
+) *Store {
+	return newStore(namespace, resyncPeriod, client, ingressClass, defaultBackend, false)
+}
+
 // New creates a new store.
 // Add informers and it handlers.
 func New(
 	namespace string,
 	resyncPeriod time.Duration,
-	client *kubernetes.Clientset,
+	client kubernetes.Interface,
 	ingressClass string,
 	recorder record.EventRecorder,
 	queue workqueue.RateLimitingInterface,
+	defaultBackend *DefaultBackend,
+	watchPods bool,
 ) *Store {
-	store := &Store{
-		informers: &Informer{},
-		listers:   &Lister{},
-	}
-
-	factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, informers.WithNamespace(namespace))
-
-	store.informers.Ingress = factory.Networking().V1().Ingresses().Informer()
-	store.listers.Ingress.Store = store.informers.Ingress.GetStore()
-
-	store.informers.Secret = factory.Core().V1().Secrets().Informer()
-	store.listers.Secret.Store = store.informers.Secret.GetStore()
-
-	store.informers.Service = factory.Core().V1().Services().Informer()
-	store.listers.Service.Store = store.informers.Service.GetStore()
-
-	store.informers.Node = factory.Core().V1().Nodes().Informer()
-	store.listers.Node.Store = store.informers.Node.GetStore()
-
-	// add indexer 'byService' to find associated ingresses by service name
-	store.informers.Ingress.AddIndexers(cache.Indexers{
-		"byService": func(obj interface{}) ([]string, error) {
-			ingress, ok := obj.(*networkv1.Ingress)
-			if !ok {
-				return nil, fmt.Errorf("unexpected type %T", obj)
-			}
-			var services []string
-			for _, rule := range ingress.Spec.Rules {
-				if rule.HTTP == nil {
-					continue
-				}
-				for _, path := range rule.HTTP.Paths {
-					services = append(services, path.Backend.Service.Name)
-				}
-			}
-			return services, nil
-		},
-	})
-
-	// add indexer 'bySecret' to find associated ingresses by secret name
-	store.informers.Ingress.AddIndexers(cache.Indexers{
-		"bySecret": func(obj interface{}) ([]string, error) {
-			ingress, ok := obj.(*networkv1.Ingress)
-			if !ok {
-				return nil, fmt.Errorf("unexpected type %T", obj)
-			}
-			var secrets []string
-			for _, tls := range ingress.Spec.TLS {
-				secrets = append(secrets, tls.SecretName)
-			}
-			return secrets, nil
-		},
-	})
+	store := newStore(namespace, resyncPeriod, client, ingressClass, defaultBackend, watchPods)
 
 	// Ingress event handlers
 	store.informers.Ingress.AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -218,6 +317,17 @@ func New(
 				recorder.Eventf(newIng, corev1.EventTypeNormal, "UpdateScheduled", key)
 				queue.Add(key)
 			}
+			// ingresses which lost host conflicts to the old version or served its canary should be resynced
+			if ingress.IsScIngress(oldIng, ingressClass) &&
+				(!ingress.IsScIngress(newIng, ingressClass) || !reflect.DeepEqual(getIngressHostPaths(oldIng), getIngressHostPaths(newIng)) ||
+					annotations.IsCanary(oldIng.Annotations) != annotations.IsCanary(newIng.Annotations)) {
+				store.enqueueConflictingIngresses(oldIng, recorder, queue)
+			}
+			// group balancer should be resynced without ingress which left the group
+			if oldGroup := annotations.GetLBGroup(oldIng.Annotations); oldGroup != "" && ingress.IsScIngress(oldIng, ingressClass) &&
+				(!ingress.IsScIngress(newIng, ingressClass) || annotations.GetLBGroup(newIng.Annotations) != oldGroup) {
+				store.enqueueGroupMembers(oldIng, ingressClass, queue)
+			}
 		},
 		DeleteFunc: func(obj interface{}) {
 			delIng := obj.(*networkv1.Ingress)
@@ -233,73 +343,350 @@ func New(
 			klog.V(3).Infof("Ingress %v deleted, enqueueing", key)
 			recorder.Eventf(delIng, corev1.EventTypeNormal, "DeleteScheduled", key)
 			queue.Add(key)
+			store.enqueueConflictingIngresses(delIng, recorder, queue)
+			store.enqueueGroupMembers(delIng, ingressClass, queue)
 		},
 	})
 
 	// Service event handlers
 	store.informers.Service.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.enqueueDependentIngresses(ByServiceIndex, obj, "created", recorder, queue)
+		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			oldSrv := oldObj.(*corev1.Service)
 			newSrv := newObj.(*corev1.Service)
 			if reflect.DeepEqual(oldSrv, newSrv) {
 				return
 			}
-			ingresses, err := store.informers.Ingress.GetIndexer().ByIndex("byService", newSrv.Name)
-			if err != nil {
-				recorder.Eventf(newSrv, corev1.EventTypeWarning, "GetIndexerFailed", err.Error())
+			store.enqueueDependentIngresses(ByServiceIndex, newObj, "changed", recorder, queue)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.enqueueDependentIngresses(ByServiceIndex, obj, "deleted", recorder, queue)
+		},
+	})
+
+	// Node event handlers, nodes are upstreams of every balancer
+	store.informers.Node.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.validateNodeAnnotations(obj.(*corev1.Node), recorder)
+			store.enqueueAllIngresses(obj, "added", ingressClass, queue)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldNode := oldObj.(*corev1.Node)
+			newNode := newObj.(*corev1.Node)
+			if reflect.DeepEqual(oldNode.Annotations, newNode.Annotations) && reflect.DeepEqual(oldNode.Labels, newNode.Labels) &&
+				reflect.DeepEqual(oldNode.Status.Addresses, newNode.Status.Addresses) {
 				return
 			}
-			sKey, err := cache.MetaNamespaceKeyFunc(newObj)
-			if err != nil {
-				recorder.Eventf(newSrv, corev1.EventTypeWarning, "CacheKey", err.Error())
+			store.validateNodeAnnotations(newNode, recorder)
+			oldIP, oldOk := getNodeUpstreamIP(oldNode)
+			newIP, newOk := getNodeUpstreamIP(newNode)
+			if oldIP == newIP && oldOk == newOk &&
+				annotations.GetUpstreamWeight(oldNode.Annotations) == annotations.GetUpstreamWeight(newNode.Annotations) {
 				return
 			}
-
-			for _, ingressObj := range ingresses {
-				ingress := ingressObj.(*networkv1.Ingress)
-				iKey, err := cache.MetaNamespaceKeyFunc(ingressObj)
-				if err != nil {
-					recorder.Eventf(ingress, corev1.EventTypeWarning, "CacheKey", err.Error())
-					return
-				}
-				klog.V(4).Infof("Service %v was changed, enqueuing associated ingress %v", sKey, iKey)
-				recorder.Eventf(ingress, corev1.EventTypeNormal, "UpdateScheduled", iKey)
-				queue.Add(iKey)
-			}
+			store.enqueueAllIngresses(newObj, "changed", ingressClass, queue)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.enqueueAllIngresses(obj, "deleted", ingressClass, queue)
 		},
 	})
 
+	// Pod event handlers, new pods could have other readiness probes
+	if store.informers.Pod != nil {
+		store.informers.Pod.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				store.enqueuePodIngresses(obj.(*corev1.Pod), queue)
+			},
+		})
+	}
+
 	// Secret event handlers
 	store.informers.Secret.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			store.enqueueDependentIngresses(BySecretIndex, obj, "created", recorder, queue)
+		},
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			oldSec := oldObj.(*corev1.Secret)
 			newSec := newObj.(*corev1.Secret)
 			if reflect.DeepEqual(oldSec, newSec) {
 				return
 			}
-			ingresses, err := store.informers.Ingress.GetIndexer().ByIndex("bySecret", newSec.Name)
-			if err != nil {
-				recorder.Eventf(newSec, corev1.EventTypeWarning, "GetIndexerFailed", err.Error())
-				return
+			store.enqueueDependentIngresses(BySecretIndex, newObj, "changed", recorder, queue)
+		},
+		DeleteFunc: func(obj interface{}) {
+			store.enqueueDependentIngresses(BySecretIndex, obj, "deleted", recorder, queue)
+		},
+	})
+
+	return store
+}
+
+// newStore creates a new store with informers, listers and indexers
+func newStore(
+	namespace string,
+	resyncPeriod time.Duration,
+	client kubernetes.Interface,
+	ingressClass string,
+	defaultBackend *DefaultBackend,
+	watchPods bool,
+) *Store {
+	store := &Store{
+		informers:      &Informer{},
+		listers:        &Lister{},
+		defaultBackend: defaultBackend,
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, resyncPeriod, informers.WithNamespace(namespace))
+
+	store.informers.Ingress = factory.Networking().V1().Ingresses().Informer()
+	store.listers.Ingress.Store = store.informers.Ingress.GetStore()
+
+	store.informers.Secret = factory.Core().V1().Secrets().Informer()
+	store.listers.Secret.Store = store.informers.Secret.GetStore()
+
+	store.informers.Service = factory.Core().V1().Services().Informer()
+	store.listers.Service.Store = store.informers.Service.GetStore()
+
+	store.informers.Node = factory.Core().V1().Nodes().Informer()
+	store.listers.Node.Store = store.informers.Node.GetStore()
+
+	// pods are needed only to derive health checks from their readiness probes,
+	// other fields aren't kept in cache
+	if watchPods {
+		store.informers.Pod = factory.Core().V1().Pods().Informer()
+		if err := store.informers.Pod.SetTransform(trimPod); err != nil {
+			klog.Errorf("setting pod transform failed: %v", err)
+		}
+		store.informers.Service.AddIndexers(cache.Indexers{
+			BySelectorIndex: func(obj interface{}) ([]string, error) {
+				svc, ok := obj.(*corev1.Service)
+				if !ok {
+					return nil, fmt.Errorf("unexpected type %T", obj)
+				}
+				var keys []string
+				for k, v := range svc.Spec.Selector {
+					keys = append(keys, selectorKey(svc.Namespace, k, v))
+				}
+				return keys, nil
+			},
+		})
+	}
+
+	// add indexers to find associated ingresses by namespaced service and secret keys
+	store.informers.Ingress.AddIndexers(cache.Indexers{
+		ByServiceIndex: func(obj interface{}) ([]string, error) {
+			ing, ok := obj.(*networkv1.Ingress)
+			if !ok {
+				return nil, fmt.Errorf("unexpected type %T", obj)
 			}
-			sKey, err := cache.MetaNamespaceKeyFunc(newObj)
-			if err != nil {
-				recorder.Eventf(newSec, corev1.EventTypeWarning, "CacheKey", err.Error())
-				return
+			return getIngressServiceKeys(ing, defaultBackend), nil
+		},
+		BySecretIndex: func(obj interface{}) ([]string, error) {
+			ing, ok := obj.(*networkv1.Ingress)
+			if !ok {
+				return nil, fmt.Errorf("unexpected type %T", obj)
 			}
-			for _, ingressObj := range ingresses {
-				ingress := ingressObj.(*networkv1.Ingress)
-				iKey, err := cache.MetaNamespaceKeyFunc(ingressObj)
-				if err != nil {
-					recorder.Eventf(ingress, corev1.EventTypeWarning, "CacheKey", err.Error())
-					return
-				}
-				klog.V(4).Infof("Secret %v was changed, enqueuing associated ingress %v", sKey, iKey)
-				recorder.Eventf(ingress, corev1.EventTypeNormal, "UpdateScheduled", iKey)
-				queue.Add(iKey)
+			return getIngressSecretKeys(ing), nil
+		},
+		ByHostPathIndex: func(obj interface{}) ([]string, error) {
+			ing, ok := obj.(*networkv1.Ingress)
+			if !ok {
+				return nil, fmt.Errorf("unexpected type %T", obj)
+			}
+			if !ingress.IsScIngress(ing, ingressClass) {
+				return nil, nil
+			}
+			var keys []string
+			for _, hp := range getIngressHostPaths(ing) {
+				keys = append(keys, hp.Host+hp.Path)
 			}
+			return keys, nil
 		},
 	})
 
 	return store
 }
+
+// enqueueDependentIngresses enqueues ingresses associated with service or secret by index
+func (s *Store) enqueueDependentIngresses(
+	indexName string,
+	obj interface{},
+	action string,
+	recorder record.EventRecorder,
+	queue workqueue.RateLimitingInterface,
+) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	runtimeObj, ok := obj.(runtime.Object)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("unexpected type %T", obj))
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		recorder.Eventf(runtimeObj, corev1.EventTypeWarning, "CacheKey", err.Error())
+		return
+	}
+	ingresses, err := s.informers.Ingress.GetIndexer().ByIndex(indexName, key)
+	if err != nil {
+		recorder.Eventf(runtimeObj, corev1.EventTypeWarning, "GetIndexerFailed", err.Error())
+		return
+	}
+
+	kind := "Service"
+	if indexName == BySecretIndex {
+		kind = "Secret"
+	}
+	for _, ingressObj := range ingresses {
+		ingress := ingressObj.(*networkv1.Ingress)
+		iKey, err := cache.MetaNamespaceKeyFunc(ingressObj)
+		if err != nil {
+			recorder.Eventf(ingress, corev1.EventTypeWarning, "CacheKey", err.Error())
+			continue
+		}
+		klog.V(4).Infof("%s %v was %s, enqueuing associated ingress %v", kind, key, action, iKey)
+		recorder.Eventf(ingress, corev1.EventTypeNormal, "UpdateScheduled", iKey)
+		queue.Add(iKey)
+	}
+}
+
+// enqueueConflictingIngresses enqueues other ingresses of controller class
+// which claim the same hosts and paths as ingress
+func (s *Store) enqueueConflictingIngresses(
+	ing *networkv1.Ingress,
+	recorder record.EventRecorder,
+	queue workqueue.RateLimitingInterface,
+) {
+	keys := make(map[string]struct{})
+	indexer := s.informers.Ingress.GetIndexer()
+	for _, hp := range getIngressHostPaths(ing) {
+		objs, err := indexer.ByIndex(ByHostPathIndex, hp.Host+hp.Path)
+		if err != nil {
+			recorder.Eventf(ing, corev1.EventTypeWarning, "GetIndexerFailed", err.Error())
+			return
+		}
+		for _, obj := range objs {
+			other := obj.(*networkv1.Ingress)
+			if other.Namespace == ing.Namespace && other.Name == ing.Name {
+				continue
+			}
+			keys[other.Namespace+"/"+other.Name] = struct{}{}
+		}
+	}
+	for key := range keys {
+		klog.V(4).Infof("Ingress %s/%s claimed hosts of %v, enqueuing", ing.Namespace, ing.Name, key)
+		queue.Add(key)
+	}
+}
+
+// validateNodeAnnotations reports invalid servers.com annotations of node
+func (s *Store) validateNodeAnnotations(node *corev1.Node, recorder record.EventRecorder) {
+	if err := annotations.ValidateNodeAnnotations(node.Annotations); err != nil {
+		recorder.Eventf(node, corev1.EventTypeWarning, "InvalidAnnotations", err.Error())
+	}
+}
+
+// enqueueAllIngresses enqueues all ingresses of controller class, node changes affect upstreams of every balancer
+func (s *Store) enqueueAllIngresses(obj interface{}, action string, ingressClass string, queue workqueue.RateLimitingInterface) {
+	var name string
+	if node, ok := obj.(*corev1.Node); ok {
+		name = node.Name
+	}
+	for _, ing := range s.ListIngress() {
+		if !ingress.IsScIngress(ing, ingressClass) {
+			continue
+		}
+		key := ing.Namespace + "/" + ing.Name
+		klog.V(4).Infof("Node %s %s, enqueuing ingress %v", name, action, key)
+		queue.Add(key)
+	}
+}
+
+// enqueuePodIngresses enqueues ingresses which use services selecting pod.
+// Pods come often, so no events are recorded, the queue drops duplicate keys.
+func (s *Store) enqueuePodIngresses(pod *corev1.Pod, queue workqueue.RateLimitingInterface) {
+	services := make(map[string]*corev1.Service)
+	for k, v := range pod.Labels {
+		objs, err := s.informers.Service.GetIndexer().ByIndex(BySelectorIndex, selectorKey(pod.Namespace, k, v))
+		if err != nil {
+			klog.Errorf("getting services of pod %s/%s failed: %v", pod.Namespace, pod.Name, err)
+			return
+		}
+		for _, obj := range objs {
+			svc := obj.(*corev1.Service)
+			services[svc.Namespace+"/"+svc.Name] = svc
+		}
+	}
+
+	keys := make(map[string]struct{})
+	for svcKey, svc := range services {
+		if !labels.SelectorFromSet(svc.Spec.Selector).Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		ingresses, err := s.informers.Ingress.GetIndexer().ByIndex(ByServiceIndex, svcKey)
+		if err != nil {
+			klog.Errorf("getting ingresses of service %s failed: %v", svcKey, err)
+			return
+		}
+		for _, obj := range ingresses {
+			ing := obj.(*networkv1.Ingress)
+			keys[ing.Namespace+"/"+ing.Name] = struct{}{}
+		}
+	}
+	for key := range keys {
+		klog.V(4).Infof("Pod %s/%s was created, enqueuing associated ingress %v", pod.Namespace, pod.Name, key)
+		queue.Add(key)
+	}
+}
+
+// selectorKey returns BySelectorIndex key of label pair in namespace
+func selectorKey(namespace, key, value string) string {
+	return namespace + "/" + key + "=" + value
+}
+
+// trimPod drops pod fields which aren't needed to derive health checks
+func trimPod(obj interface{}) (interface{}, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return obj, nil
+	}
+	trimmed := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            pod.Name,
+			Namespace:       pod.Namespace,
+			UID:             pod.UID,
+			ResourceVersion: pod.ResourceVersion,
+			Labels:          pod.Labels,
+		},
+	}
+	for _, c := range pod.Spec.Containers {
+		trimmed.Spec.Containers = append(trimmed.Spec.Containers, corev1.Container{
+			Name:           c.Name,
+			Ports:          c.Ports,
+			ReadinessProbe: c.ReadinessProbe,
+		})
+	}
+	return trimmed, nil
+}
+
+// enqueueGroupMembers enqueues other ingresses of controller class from the same load balancer group as ing
+func (s *Store) enqueueGroupMembers(ing *networkv1.Ingress, ingressClass string, queue workqueue.RateLimitingInterface) {
+	group := annotations.GetLBGroup(ing.Annotations)
+	if group == "" {
+		return
+	}
+	for _, other := range s.ListIngress() {
+		if other.Namespace != ing.Namespace || other.Name == ing.Name {
+			continue
+		}
+		if !ingress.IsScIngress(other, ingressClass) || annotations.GetLBGroup(other.Annotations) != group {
+			continue
+		}
+		key := other.Namespace + "/" + other.Name
+		klog.V(4).Infof("Ingress %s/%s left group %s, enqueuing member %v", ing.Namespace, ing.Name, group, key)
+		queue.Add(key)
+	}
+}
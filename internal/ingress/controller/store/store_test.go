@@ -1,14 +1,20 @@
 package store
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	. "github.com/onsi/gomega"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
 var (
@@ -31,7 +37,7 @@ var (
 
 func TestGetIngress(t *testing.T) {
 	g := NewWithT(t)
-	s := New("", time.Second, nil, "", nil, nil)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
 	s.listers.Ingress.Add(scIngress)
 
 	ingress, err := s.GetIngress("test-ingress")
@@ -41,7 +47,7 @@ func TestGetIngress(t *testing.T) {
 
 func TestGetSecret(t *testing.T) {
 	g := NewWithT(t)
-	s := New("", time.Second, nil, "", nil, nil)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
 
 	testSecret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -58,7 +64,7 @@ func TestGetSecret(t *testing.T) {
 
 func TestListIngress(t *testing.T) {
 	g := NewWithT(t)
-	s := New("", time.Second, nil, "", nil, nil)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
 
 	s.listers.Ingress.Add(scIngress)
 	s.listers.Ingress.Add(nonScIngress)
@@ -70,7 +76,7 @@ func TestListIngress(t *testing.T) {
 
 func TestGetService(t *testing.T) {
 	g := NewWithT(t)
-	s := New("", time.Second, nil, "", nil, nil)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
 
 	testService := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
@@ -87,7 +93,7 @@ func TestGetService(t *testing.T) {
 
 func TestGetNodesIpList(t *testing.T) {
 	g := NewWithT(t)
-	s := New("", time.Second, nil, "", nil, nil)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
 
 	masterNode := &corev1.Node{
 		ObjectMeta: metav1.ObjectMeta{
@@ -133,9 +139,56 @@ func TestGetNodesIpList(t *testing.T) {
 	g.Expect(nodesIpList).To(ConsistOf("192.168.1.2", "192.168.1.3"))
 }
 
+func TestGetNodeWeights(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
+
+	newNode := func(name, ip string, annotations map[string]string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}},
+			},
+		}
+	}
+	master := newNode("master", "192.168.1.1", nil)
+	master.Labels = map[string]string{MasterNodeAnnotationKey: ""}
+	s.listers.Node.Add(master)
+	s.listers.Node.Add(newNode("node1", "192.168.1.2", nil))
+	s.listers.Node.Add(newNode("node2", "192.168.1.3", map[string]string{annotations.UpstreamWeight: "3"}))
+	s.listers.Node.Add(newNode("node3", "192.168.1.4", map[string]string{annotations.UpstreamDrain: "true"}))
+
+	g.Expect(s.GetNodeWeights()).To(Equal(map[string]int{
+		"192.168.1.2": 1,
+		"192.168.1.3": 3,
+		"192.168.1.4": 0,
+	}))
+}
+
+func TestEnqueueAllIngresses(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, scIngressClassName, nil, nil, nil, false)
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	sc := scIngress.DeepCopy()
+	sc.Namespace = "default"
+	other := sc.DeepCopy()
+	other.Name = "other-class"
+	other.Spec.IngressClassName = &nonScIngressClassName
+	s.listers.Ingress.Add(sc)
+	s.listers.Ingress.Add(other)
+
+	s.enqueueAllIngresses(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}, "changed", scIngressClassName, queue)
+	g.Expect(queue.Len()).To(Equal(1))
+	item, _ := queue.Get()
+	g.Expect(item).To(Equal("default/" + sc.Name))
+	queue.Done(item)
+}
+
 func TestGetIngressServiceInfo(t *testing.T) {
 	g := NewWithT(t)
-	s := New("", time.Second, nil, "", nil, nil)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
 
 	node1 := &corev1.Node{
 		Status: corev1.NodeStatus{
@@ -211,5 +264,557 @@ func TestGetIngressServiceInfo(t *testing.T) {
 
 	_, err = s.GetIngressHostsInfo(ingress)
 	expectedErr := fmt.Errorf("service %s has no NodePort (only NodePort/LoadBalancer supported)", serviceName)
-	g.Expect(err).To(Equal(expectedErr))
+	g.Expect(err).To(MatchError(expectedErr.Error()))
+	g.Expect(err).To(BeAssignableToTypeOf(&NoNodePortError{}))
+}
+
+func TestGetIngressHostsInfoDefaultBackend(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
+
+	s.listers.Node.Add(&corev1.Node{
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.1.1"}},
+		},
+	})
+	newService := func(namespace, name string, nodePort int32) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 80, NodePort: nodePort}},
+			},
+		}
+	}
+	s.listers.Service.Add(newService("default", "test-service", 30000))
+	s.listers.Service.Add(newService("default", "test-default", 30001))
+	s.listers.Service.Add(newService("kube-system", "controller-default", 30002))
+
+	ingress := &networkv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ingress", Namespace: "default"},
+		Spec: networkv1.IngressSpec{
+			DefaultBackend: &networkv1.IngressBackend{
+				Service: &networkv1.IngressServiceBackend{
+					Name: "test-default",
+					Port: networkv1.ServiceBackendPort{Number: 80},
+				},
+			},
+			Rules: []networkv1.IngressRule{
+				{
+					IngressRuleValue: networkv1.IngressRuleValue{
+						HTTP: &networkv1.HTTPIngressRuleValue{
+							Paths: []networkv1.HTTPIngressPath{
+								{
+									Path: "/api",
+									Backend: networkv1.IngressBackend{
+										Service: &networkv1.IngressServiceBackend{
+											Name: "test-service",
+											Port: networkv1.ServiceBackendPort{Number: 80},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	hostsInfo, err := s.GetIngressHostsInfo(ingress)
+	g.Expect(err).To(BeNil())
+	g.Expect(hostsInfo).To(HaveLen(1))
+	paths := hostsInfo[DefaultBackendHost].Paths
+	g.Expect(paths).To(HaveLen(2))
+	g.Expect(paths[0].Path).To(Equal("/api"))
+	g.Expect(paths[0].DefaultBackend).To(BeFalse())
+	g.Expect(paths[1].Path).To(Equal("/"))
+	g.Expect(paths[1].Service.Name).To(Equal("test-default"))
+	g.Expect(paths[1].NodePort).To(Equal(30001))
+	g.Expect(paths[1].DefaultBackend).To(BeTrue())
+
+	// controller default backend is used when ingress doesn't have one
+	s.defaultBackend = &DefaultBackend{Namespace: "kube-system", Name: "controller-default"}
+	ingress.Spec.DefaultBackend = nil
+	hostsInfo, err = s.GetIngressHostsInfo(ingress)
+	g.Expect(err).To(BeNil())
+	paths = hostsInfo[DefaultBackendHost].Paths
+	g.Expect(paths).To(HaveLen(2))
+	g.Expect(paths[1].Service.Name).To(Equal("controller-default"))
+	g.Expect(paths[1].NodePort).To(Equal(30002))
+
+	// missing controller default backend doesn't fail ingress
+	s.defaultBackend = &DefaultBackend{Namespace: "kube-system", Name: "missing"}
+	hostsInfo, err = s.GetIngressHostsInfo(ingress)
+	g.Expect(err).To(BeNil())
+	g.Expect(hostsInfo[DefaultBackendHost].Paths).To(HaveLen(1))
+}
+
+func TestParseDefaultBackend(t *testing.T) {
+	g := NewWithT(t)
+
+	b, err := ParseDefaultBackend("kube-system/default-backend")
+	g.Expect(err).To(BeNil())
+	g.Expect(*b).To(Equal(DefaultBackend{Namespace: "kube-system", Name: "default-backend"}))
+
+	b, err = ParseDefaultBackend("kube-system/default-backend:8080")
+	g.Expect(err).To(BeNil())
+	g.Expect(b.Port.Number).To(Equal(int32(8080)))
+
+	b, err = ParseDefaultBackend("kube-system/default-backend:http")
+	g.Expect(err).To(BeNil())
+	g.Expect(b.Port.Name).To(Equal("http"))
+
+	for _, value := range []string{"default-backend", "/default-backend", "kube-system/", "a/b/c", "kube-system/default-backend:", "kube-system/default-backend:0", "kube-system/default-backend:Http_Port"} {
+		_, err = ParseDefaultBackend(value)
+		g.Expect(err).To(HaveOccurred(), value)
+	}
+}
+
+func TestGetServiceNodePort(t *testing.T) {
+	g := NewWithT(t)
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-service"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Name: "http", Port: 80, NodePort: 30000},
+				{Name: "https", Port: 443, NodePort: 30001},
+				{Name: "metrics", Port: 9090},
+			},
+		},
+	}
+
+	nodePort, err := GetServiceNodePort(svc, networkv1.ServiceBackendPort{Number: 443})
+	g.Expect(err).To(BeNil())
+	g.Expect(nodePort).To(Equal(int32(30001)))
+
+	nodePort, err = GetServiceNodePort(svc, networkv1.ServiceBackendPort{Name: "http"})
+	g.Expect(err).To(BeNil())
+	g.Expect(nodePort).To(Equal(int32(30000)))
+
+	_, err = GetServiceNodePort(svc, networkv1.ServiceBackendPort{Name: "grpc"})
+	g.Expect(err).To(MatchError(`service test-service: port "grpc" not found`))
+	g.Expect(err).To(BeAssignableToTypeOf(&PortNotFoundError{}))
+
+	_, err = GetServiceNodePort(svc, networkv1.ServiceBackendPort{Number: 8080})
+	g.Expect(err).To(MatchError("service test-service: port 8080 not found"))
+	g.Expect(err).To(BeAssignableToTypeOf(&PortNotFoundError{}))
+
+	_, err = GetServiceNodePort(svc, networkv1.ServiceBackendPort{Name: "metrics"})
+	g.Expect(err).To(BeAssignableToTypeOf(&NoNodePortError{}))
+}
+
+func TestGetIngressHostsInfoMissingService(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
+
+	ingress := &networkv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ingress", Namespace: "default"},
+		Spec: networkv1.IngressSpec{
+			DefaultBackend: &networkv1.IngressBackend{
+				Service: &networkv1.IngressServiceBackend{Name: "missing", Port: networkv1.ServiceBackendPort{Name: "http"}},
+			},
+		},
+	}
+
+	_, err := s.GetIngressHostsInfo(ingress)
+	g.Expect(err).To(MatchError(`error getting service "default/missing": no object matching key "default/missing" in local store`))
+	var notFound *ServiceNotFoundError
+	g.Expect(errors.As(err, &notFound)).To(BeTrue())
+	g.Expect(errors.As(err, new(NotExistsError))).To(BeTrue())
+}
+
+func TestGetIngressHostsInfoResourceBackend(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, "", nil, nil, nil, false)
+
+	resource := networkv1.IngressBackend{
+		Resource: &corev1.TypedLocalObjectReference{Kind: "StorageBucket", Name: "static"},
+	}
+	ingress := &networkv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-ingress", Namespace: "default"},
+		Spec: networkv1.IngressSpec{
+			Rules: []networkv1.IngressRule{
+				{
+					Host: "example.com",
+					IngressRuleValue: networkv1.IngressRuleValue{
+						HTTP: &networkv1.HTTPIngressRuleValue{
+							Paths: []networkv1.HTTPIngressPath{{Path: "/static", Backend: resource}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// indexers must not panic on resource backends
+	g.Expect(s.informers.Ingress.GetIndexer().Add(ingress)).To(Succeed())
+
+	_, err := s.GetIngressHostsInfo(ingress)
+	g.Expect(err).To(MatchError(`host "example.com" path "/static": only service backends are supported`))
+	g.Expect(err).To(BeAssignableToTypeOf(&UnsupportedBackendError{}))
+
+	ingress.Spec.Rules = nil
+	ingress.Spec.DefaultBackend = &resource
+	_, err = s.GetIngressHostsInfo(ingress)
+	g.Expect(err).To(MatchError("default backend: only service backends are supported"))
+}
+
+func FuzzGetIngressHostsInfo(f *testing.F) {
+	f.Add([]byte(`{"spec":{"rules":[{"host":"example.com","http":{"paths":[{"path":"/","pathType":"Prefix","backend":{"service":{"name":"test-service","port":{"number":80}}}}]}}]}}`))
+	f.Add([]byte(`{"spec":{"rules":[{"http":{"paths":[{"path":"/a","backend":{"service":{"name":"test-service","port":{"name":"http"}}}}]}}]}}`))
+	f.Add([]byte(`{"spec":{"defaultBackend":{"resource":{"kind":"Bucket","name":"static"}},"rules":[{"host":"foo.com"}]}}`))
+	f.Add([]byte(`{"spec":{"rules":[{"host":"foo.com","http":{"paths":[{"path":"","backend":{}}]}}],"tls":[{"hosts":["foo.com"]}]}}`))
+	f.Add([]byte(`{"spec":{"defaultBackend":{"service":{"name":"missing"}}}}`))
+
+	s := New("", time.Second, nil, "", nil, nil, &DefaultBackend{Namespace: "default", Name: "test-service"}, false)
+	s.listers.Node.Add(&corev1.Node{
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.1.1"}},
+		},
+	})
+	s.listers.Service.Add(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "default"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30000}},
+		},
+	})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ingress := &networkv1.Ingress{}
+		if err := json.Unmarshal(data, ingress); err != nil {
+			t.Skip()
+		}
+		ingress.Name = "fuzz-ingress"
+		ingress.Namespace = "default"
+
+		indexer := s.informers.Ingress.GetIndexer()
+		if err := indexer.Add(ingress); err != nil {
+			t.Fatal(err)
+		}
+		defer indexer.Delete(ingress)
+
+		hostsInfo, err := s.GetIngressHostsInfo(ingress)
+		if err != nil {
+			return
+		}
+		for _, hInfo := range hostsInfo {
+			for _, p := range hInfo.Paths {
+				if p.Service == nil || p.NodePort == 0 {
+					t.Fatalf("path %q has no service backend", p.Path)
+				}
+			}
+		}
+	})
+}
+
+func TestEnqueueDependentIngresses(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, "", nil, nil, &DefaultBackend{Namespace: "kube-system", Name: "default-backend"}, false)
+	recorder := record.NewFakeRecorder(10)
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	newIngress := func(namespace string) *networkv1.Ingress {
+		return &networkv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "test-ingress",
+				Namespace:   namespace,
+				Annotations: map[string]string{annotations.LBCertificatePrefix + "foo.com": "annotation-secret"},
+			},
+			Spec: networkv1.IngressSpec{
+				TLS: []networkv1.IngressTLS{{Hosts: []string{"example.com"}, SecretName: "test-secret"}},
+				Rules: []networkv1.IngressRule{
+					{
+						Host: "example.com",
+						IngressRuleValue: networkv1.IngressRuleValue{
+							HTTP: &networkv1.HTTPIngressRuleValue{
+								Paths: []networkv1.HTTPIngressPath{
+									{
+										Path: "/",
+										Backend: networkv1.IngressBackend{
+											Service: &networkv1.IngressServiceBackend{Name: "test-service"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	indexer := s.informers.Ingress.GetIndexer()
+	g.Expect(indexer.Add(newIngress("a"))).To(Succeed())
+	g.Expect(indexer.Add(newIngress("b"))).To(Succeed())
+
+	keys, err := indexer.IndexKeys(ByServiceIndex, "a/test-service")
+	g.Expect(err).To(BeNil())
+	g.Expect(keys).To(ConsistOf("a/test-ingress"))
+	keys, err = indexer.IndexKeys(ByServiceIndex, "kube-system/default-backend")
+	g.Expect(err).To(BeNil())
+	g.Expect(keys).To(ConsistOf("a/test-ingress", "b/test-ingress"))
+	keys, err = indexer.IndexKeys(BySecretIndex, "b/annotation-secret")
+	g.Expect(err).To(BeNil())
+	g.Expect(keys).To(ConsistOf("b/test-ingress"))
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "test-service", Namespace: "a"}}
+	s.enqueueDependentIngresses(ByServiceIndex, svc, "created", recorder, queue)
+	g.Expect(queue.Len()).To(Equal(1))
+	item, _ := queue.Get()
+	g.Expect(item).To(Equal("a/test-ingress"))
+	queue.Done(item)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test-secret", Namespace: "b"}}
+	s.enqueueDependentIngresses(BySecretIndex, cache.DeletedFinalStateUnknown{Key: "b/test-secret", Obj: secret}, "deleted", recorder, queue)
+	g.Expect(queue.Len()).To(Equal(1))
+	item, _ = queue.Get()
+	g.Expect(item).To(Equal("b/test-ingress"))
+	queue.Done(item)
+
+	other := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other-secret", Namespace: "a"}}
+	s.enqueueDependentIngresses(BySecretIndex, other, "created", recorder, queue)
+	g.Expect(queue.Len()).To(Equal(0))
+}
+
+func TestGetHostConflicts(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, scIngressClassName, nil, nil, nil, false)
+
+	now := time.Now()
+	newIngress := func(name string, created time.Time, class string, paths ...string) *networkv1.Ingress {
+		var httpPaths []networkv1.HTTPIngressPath
+		for _, p := range paths {
+			httpPaths = append(httpPaths, networkv1.HTTPIngressPath{Path: p})
+		}
+		return &networkv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: networkv1.IngressSpec{
+				IngressClassName: &class,
+				Rules: []networkv1.IngressRule{
+					{
+						Host: "example.com",
+						IngressRuleValue: networkv1.IngressRuleValue{
+							HTTP: &networkv1.HTTPIngressRuleValue{Paths: httpPaths},
+						},
+					},
+					{
+						IngressRuleValue: networkv1.IngressRuleValue{
+							HTTP: &networkv1.HTTPIngressRuleValue{Paths: httpPaths},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	oldest := newIngress("oldest", now.Add(-time.Hour), scIngressClassName, "/", "/api")
+	older := newIngress("older", now.Add(-time.Minute), scIngressClassName, "/api", "/app")
+	newest := newIngress("newest", now, scIngressClassName, "/", "/app", "/new")
+	otherClass := newIngress("other-class", now.Add(-2*time.Hour), nonScIngressClassName, "/new")
+
+	indexer := s.informers.Ingress.GetIndexer()
+	for _, ing := range []*networkv1.Ingress{oldest, older, newest, otherClass} {
+		g.Expect(indexer.Add(ing)).To(Succeed())
+	}
+
+	g.Expect(s.GetHostConflicts(oldest)).To(BeEmpty())
+	g.Expect(s.GetHostConflicts(older)).To(Equal([]HostConflict{
+		{Host: "example.com", Path: "/api", Owner: "default/oldest"},
+	}))
+	g.Expect(s.GetHostConflicts(newest)).To(Equal([]HostConflict{
+		{Host: "example.com", Path: "/", Owner: "default/oldest"},
+		{Host: "example.com", Path: "/app", Owner: "default/older"},
+	}))
+
+	// ingress which isn't created yet loses to all existing ingresses
+	created := newIngress("not-created", time.Time{}, scIngressClassName, "/new")
+	g.Expect(s.GetHostConflicts(created)).To(Equal([]HostConflict{
+		{Host: "example.com", Path: "/new", Owner: "default/newest"},
+	}))
+
+	// ingresses with the same timestamp are ordered by name
+	twin := newIngress("a-twin", now, scIngressClassName, "/new")
+	g.Expect(s.GetHostConflicts(twin)).To(BeEmpty())
+	g.Expect(indexer.Add(twin)).To(Succeed())
+	g.Expect(s.GetHostConflicts(newest)).To(ContainElement(
+		HostConflict{Host: "example.com", Path: "/new", Owner: "default/a-twin"},
+	))
+}
+
+func TestGetHostPathIngresses(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, scIngressClassName, nil, nil, nil, false)
+
+	now := time.Now()
+	newIngress := func(name string, created time.Time, canary bool, paths ...string) *networkv1.Ingress {
+		var httpPaths []networkv1.HTTPIngressPath
+		for _, p := range paths {
+			httpPaths = append(httpPaths, networkv1.HTTPIngressPath{Path: p})
+		}
+		ing := &networkv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "default",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: networkv1.IngressSpec{
+				IngressClassName: &scIngressClassName,
+				Rules: []networkv1.IngressRule{
+					{
+						Host: "example.com",
+						IngressRuleValue: networkv1.IngressRuleValue{
+							HTTP: &networkv1.HTTPIngressRuleValue{Paths: httpPaths},
+						},
+					},
+				},
+			},
+		}
+		if canary {
+			ing.Annotations = map[string]string{annotations.Canary: "true"}
+		}
+		return ing
+	}
+
+	stable := newIngress("stable", now.Add(-time.Hour), false, "/", "/api")
+	other := newIngress("other", now.Add(-time.Minute), false, "/api")
+	canary := newIngress("canary", now, true, "/", "/api")
+	unrelated := newIngress("unrelated", now, false, "/app")
+
+	indexer := s.informers.Ingress.GetIndexer()
+	for _, ing := range []*networkv1.Ingress{stable, other, canary, unrelated} {
+		g.Expect(indexer.Add(ing)).To(Succeed())
+	}
+
+	g.Expect(s.GetHostPathIngresses(canary)).To(Equal([]*networkv1.Ingress{stable, other}))
+	g.Expect(s.GetHostPathIngresses(stable)).To(Equal([]*networkv1.Ingress{other, canary}))
+	g.Expect(s.GetHostPathIngresses(unrelated)).To(BeEmpty())
+
+	// canary doesn't conflict with ingresses it shares hosts and paths with
+	g.Expect(s.GetHostConflicts(canary)).To(BeEmpty())
+	g.Expect(s.GetHostConflicts(other)).To(Equal([]HostConflict{
+		{Host: "example.com", Path: "/api", Owner: "default/stable"},
+	}))
+}
+
+func TestGetServicePods(t *testing.T) {
+	g := NewWithT(t)
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "web"}},
+	}
+	newPod := func(name, namespace, app string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}}}
+	}
+
+	t.Run("Pods aren't watched", func(t *testing.T) {
+		s := New("", time.Second, nil, "", nil, nil, nil, false)
+		g.Expect(s.GetServicePods(svc)).To(BeNil())
+	})
+
+	t.Run("Selected pods", func(t *testing.T) {
+		s := New("", time.Second, nil, "", nil, nil, nil, true)
+		indexer := s.informers.Pod.GetIndexer()
+		web2 := newPod("web-2", "default", "web")
+		web1 := newPod("web-1", "default", "web")
+		indexer.Add(web2)
+		indexer.Add(web1)
+		indexer.Add(newPod("db-1", "default", "db"))
+		indexer.Add(newPod("web-1", "other", "web"))
+
+		g.Expect(s.GetServicePods(svc)).To(Equal([]*corev1.Pod{web1, web2}))
+
+		noSelector := svc.DeepCopy()
+		noSelector.Spec.Selector = nil
+		g.Expect(s.GetServicePods(noSelector)).To(BeNil())
+	})
+}
+
+func TestEnqueuePodIngresses(t *testing.T) {
+	g := NewWithT(t)
+	s := New("", time.Second, nil, scIngressClassName, nil, nil, nil, true)
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	newService := func(name string, selector map[string]string) *corev1.Service {
+		return &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.ServiceSpec{Selector: selector},
+		}
+	}
+	newIngress := func(name, service string) *networkv1.Ingress {
+		ing := scIngress.DeepCopy()
+		ing.Name = name
+		ing.Namespace = "default"
+		ing.Spec.DefaultBackend = &networkv1.IngressBackend{
+			Service: &networkv1.IngressServiceBackend{Name: service, Port: networkv1.ServiceBackendPort{Number: 80}},
+		}
+		return ing
+	}
+	serviceIndexer := s.informers.Service.GetIndexer()
+	serviceIndexer.Add(newService("web", map[string]string{"app": "web"}))
+	serviceIndexer.Add(newService("web-v2", map[string]string{"app": "web", "version": "v2"}))
+	serviceIndexer.Add(newService("db", map[string]string{"app": "db"}))
+	ingressIndexer := s.informers.Ingress.GetIndexer()
+	ingressIndexer.Add(newIngress("web", "web"))
+	ingressIndexer.Add(newIngress("web-v2", "web-v2"))
+	ingressIndexer.Add(newIngress("db", "db"))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "web-1",
+		Namespace: "default",
+		Labels:    map[string]string{"app": "web", "version": "v1"},
+	}}
+	s.enqueuePodIngresses(pod, queue)
+	g.Expect(queue.Len()).To(Equal(1))
+	item, _ := queue.Get()
+	g.Expect(item).To(Equal("default/web"))
+	queue.Done(item)
+
+	pod.Namespace = "other"
+	s.enqueuePodIngresses(pod, queue)
+	g.Expect(queue.Len()).To(Equal(0))
+}
+
+func TestTrimPod(t *testing.T) {
+	g := NewWithT(t)
+	probe := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/healthz"}}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "web-1",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "web"},
+			Annotations: map[string]string{"a": "b"},
+		},
+		Spec: corev1.PodSpec{
+			NodeName: "node1",
+			Containers: []corev1.Container{{
+				Name:           "web",
+				Image:          "nginx",
+				Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+				ReadinessProbe: probe,
+			}},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+
+	trimmed, err := trimPod(pod)
+	g.Expect(err).To(BeNil())
+	g.Expect(trimmed).To(Equal(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name:           "web",
+				Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+				ReadinessProbe: probe,
+			}},
+		},
+	}))
+
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/web-1"}
+	g.Expect(trimPod(tombstone)).To(Equal(tombstone))
 }
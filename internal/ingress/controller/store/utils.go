@@ -2,17 +2,65 @@ package store
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 
 	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
 )
 
 // PathInfo represents info about a path in the ingress controller
 type PathInfo struct {
 	Path     string
+	PathType *networkv1.PathType
 	Service  *corev1.Service
 	NodePort int
 	NodeIps  []string
+	// NodeWeights are upstream weights of node ips, 0 for drained nodes
+	NodeWeights map[string]int
+	// DefaultBackend is true for the catch-all path of ingress or controller default backend
+	DefaultBackend bool
+}
+
+// DefaultBackendHost is a key of hosts info for rules without host and default backend paths
+const DefaultBackendHost = ""
+
+// DefaultBackend represents a controller wide default backend service
+type DefaultBackend struct {
+	Namespace string
+	Name      string
+	// Port is optional, could be a number or a name, the first service port is used if it's empty
+	Port networkv1.ServiceBackendPort
+}
+
+// ParseDefaultBackend parses default backend service in namespace/name[:port] format,
+// port could be a number or a name
+func ParseDefaultBackend(value string) (*DefaultBackend, error) {
+	svc, port, hasPort := strings.Cut(value, ":")
+	namespace, name, ok := strings.Cut(svc, "/")
+	if !ok || namespace == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("default backend service %q must be in namespace/name[:port] format", value)
+	}
+
+	b := &DefaultBackend{Namespace: namespace, Name: name}
+	if hasPort {
+		number, err := strconv.ParseInt(port, 10, 32)
+		switch {
+		case err != nil && len(validation.IsValidPortName(port)) == 0:
+			b.Port.Name = port
+		case err != nil || number <= 0:
+			return nil, fmt.Errorf("default backend service %q has invalid port %q", value, port)
+		default:
+			b.Port.Number = int32(number)
+		}
+	}
+	return b, nil
 }
 
 // HostInfo represents info about a host in the ingress controller
@@ -21,10 +69,13 @@ type HostInfo struct {
 	Paths []PathInfo
 }
 
-// getIngressHostsInfo get hosts info from ingress
-func getIngressHostsInfo(ingress *networkv1.Ingress, store Storer) (map[string]HostInfo, error) {
+// getIngressHostsInfo get hosts info from ingress.
+// Rules without host and default backend are stored under DefaultBackendHost,
+// controller default backend is used if ingress doesn't have its own.
+func getIngressHostsInfo(ingress *networkv1.Ingress, store Storer, defaultBackend *DefaultBackend) (map[string]HostInfo, error) {
 	hostsInfo := make(map[string]HostInfo)
 	nodeIps := store.GetNodesIpList()
+	nodeWeights := store.GetNodeWeights()
 
 	for _, rule := range ingress.Spec.Rules {
 		if rule.HTTP == nil {
@@ -35,37 +86,264 @@ func getIngressHostsInfo(ingress *networkv1.Ingress, store Storer) (map[string]H
 		hInfo.Host = rule.Host
 
 		for _, path := range rule.HTTP.Paths {
-			svc, err := store.GetService(ingress.Namespace + "/" + path.Backend.Service.Name)
+			if path.Backend.Service == nil {
+				return nil, &UnsupportedBackendError{Host: rule.Host, Path: path.Path}
+			}
+			svc, err := getService(store, ingress.Namespace+"/"+path.Backend.Service.Name)
 			if err != nil {
-				return nil, fmt.Errorf("error getting service: %v", err)
+				return nil, err
 			}
 
-			var nodePort int32
-			found := false
-			for _, port := range svc.Spec.Ports {
-				if port.Port == path.Backend.Service.Port.Number {
-					if port.NodePort == 0 {
-						return nil, fmt.Errorf("service %s has no NodePort (only NodePort/LoadBalancer supported)", svc.Name)
-					}
-					nodePort = port.NodePort
-					found = true
-					break
-				}
-			}
-			if !found {
-				return nil, fmt.Errorf("service %s: port %d not found", svc.Name, path.Backend.Service.Port.Number)
+			nodePort, err := GetServiceNodePort(svc, path.Backend.Service.Port)
+			if err != nil {
+				return nil, err
 			}
 
 			hInfo.Paths = append(hInfo.Paths, PathInfo{
-				Path:     path.Path,
-				Service:  svc,
-				NodePort: int(nodePort),
-				NodeIps:  nodeIps,
+				Path:        path.Path,
+				PathType:    path.PathType,
+				Service:     svc,
+				NodePort:    int(nodePort),
+				NodeIps:     nodeIps,
+				NodeWeights: nodeWeights,
 			})
 		}
 
 		hostsInfo[rule.Host] = hInfo
 	}
 
+	var (
+		svc      *corev1.Service
+		nodePort int32
+		err      error
+	)
+	switch {
+	case ingress.Spec.DefaultBackend != nil && ingress.Spec.DefaultBackend.Service == nil:
+		return nil, &UnsupportedBackendError{Default: true}
+	case ingress.Spec.DefaultBackend != nil:
+		backend := ingress.Spec.DefaultBackend.Service
+		svc, err = getService(store, ingress.Namespace+"/"+backend.Name)
+		if err != nil {
+			return nil, err
+		}
+		nodePort, err = GetServiceNodePort(svc, backend.Port)
+		if err != nil {
+			return nil, err
+		}
+	case defaultBackend != nil:
+		// controller default backend shouldn't break ingress sync, so it's skipped on errors
+		svc, err = getService(store, defaultBackend.Namespace+"/"+defaultBackend.Name)
+		if err != nil {
+			klog.Errorf("default backend: %v", err)
+			return hostsInfo, nil
+		}
+		port := defaultBackend.Port
+		if port.Number == 0 && port.Name == "" && len(svc.Spec.Ports) != 0 {
+			port.Number = svc.Spec.Ports[0].Port
+		}
+		nodePort, err = GetServiceNodePort(svc, port)
+		if err != nil {
+			klog.Errorf("default backend: %v", err)
+			return hostsInfo, nil
+		}
+	default:
+		return hostsInfo, nil
+	}
+
+	pathType := networkv1.PathTypeImplementationSpecific
+	hInfo := hostsInfo[DefaultBackendHost]
+	hInfo.Paths = append(hInfo.Paths, PathInfo{
+		Path:           "/",
+		PathType:       &pathType,
+		Service:        svc,
+		NodePort:       int(nodePort),
+		NodeIps:        nodeIps,
+		NodeWeights:    nodeWeights,
+		DefaultBackend: true,
+	})
+	hostsInfo[DefaultBackendHost] = hInfo
+
 	return hostsInfo, nil
 }
+
+// HostPath represents host and path of an ingress rule
+type HostPath struct {
+	Host string
+	Path string
+}
+
+// HostConflict describes host and path of ingress claimed by an older ingress
+type HostConflict struct {
+	Host string
+	Path string
+	// Owner is a namespaced key of ingress which claimed host and path
+	Owner string
+}
+
+// Error implements the error interface.
+func (c HostConflict) Error() string {
+	return fmt.Sprintf("host and path %q already claimed by ingress %s", c.Host+c.Path, c.Owner)
+}
+
+// getIngressHostPaths returns unique hosts and paths of ingress rules.
+// Rules without host aren't included, they are served only by the balancer of ingress.
+func getIngressHostPaths(ing *networkv1.Ingress) []HostPath {
+	var res []HostPath
+	seen := make(map[HostPath]struct{})
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host == "" || rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			hp := HostPath{Host: rule.Host, Path: path.Path}
+			if _, ok := seen[hp]; ok {
+				continue
+			}
+			seen[hp] = struct{}{}
+			res = append(res, hp)
+		}
+	}
+	return res
+}
+
+// IsOlderIngress checks if ingress a is older than b by creation timestamp.
+// Ingress without timestamp isn't created yet and is the newest,
+// ingresses with the same timestamp are ordered by namespaced name.
+func IsOlderIngress(a, b *networkv1.Ingress) bool {
+	aTime, bTime := a.CreationTimestamp, b.CreationTimestamp
+	switch {
+	case aTime.IsZero() && !bTime.IsZero():
+		return false
+	case !aTime.IsZero() && bTime.IsZero():
+		return true
+	case !aTime.Equal(&bTime):
+		return aTime.Before(&bTime)
+	}
+	return a.Namespace+"/"+a.Name < b.Namespace+"/"+b.Name
+}
+
+// ServiceNotFoundError is returned when a backend service can't be fetched
+type ServiceNotFoundError struct {
+	Key string
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ServiceNotFoundError) Error() string {
+	return fmt.Sprintf("error getting service %q: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ServiceNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// PortNotFoundError is returned when a service doesn't have a backend port
+type PortNotFoundError struct {
+	Service string
+	Port    networkv1.ServiceBackendPort
+}
+
+// Error implements the error interface.
+func (e *PortNotFoundError) Error() string {
+	if e.Port.Name != "" {
+		return fmt.Sprintf("service %s: port %q not found", e.Service, e.Port.Name)
+	}
+	return fmt.Sprintf("service %s: port %d not found", e.Service, e.Port.Number)
+}
+
+// NoNodePortError is returned when a backend port of service has no NodePort
+type NoNodePortError struct {
+	Service string
+}
+
+// Error implements the error interface.
+func (e *NoNodePortError) Error() string {
+	return fmt.Sprintf("service %s has no NodePort (only NodePort/LoadBalancer supported)", e.Service)
+}
+
+// UnsupportedBackendError is returned when ingress backend isn't a service, e.g. a resource backend
+type UnsupportedBackendError struct {
+	Host string
+	Path string
+	// Default is true for ingress default backend
+	Default bool
+}
+
+// Error implements the error interface.
+func (e *UnsupportedBackendError) Error() string {
+	if e.Default {
+		return "default backend: only service backends are supported"
+	}
+	return fmt.Sprintf("host %q path %q: only service backends are supported", e.Host, e.Path)
+}
+
+// getService returns service by key, wraps errors into ServiceNotFoundError
+func getService(store Storer, key string) (*corev1.Service, error) {
+	svc, err := store.GetService(key)
+	if err != nil {
+		return nil, &ServiceNotFoundError{Key: key, Err: err}
+	}
+	return svc, nil
+}
+
+// GetServiceNodePort returns NodePort of service port used as ingress backend.
+// Port is matched by name if it's set, otherwise by number.
+func GetServiceNodePort(svc *corev1.Service, backendPort networkv1.ServiceBackendPort) (int32, error) {
+	for _, port := range svc.Spec.Ports {
+		if backendPort.Name != "" {
+			if port.Name != backendPort.Name {
+				continue
+			}
+		} else if port.Port != backendPort.Number {
+			continue
+		}
+		if port.NodePort == 0 {
+			return 0, &NoNodePortError{Service: svc.Name}
+		}
+		return port.NodePort, nil
+	}
+	return 0, &PortNotFoundError{Service: svc.Name, Port: backendPort}
+}
+
+// getIngressServiceKeys returns namespaced keys of ingress backend services,
+// controller default backend is included if ingress doesn't have its own
+func getIngressServiceKeys(ing *networkv1.Ingress, defaultBackend *DefaultBackend) []string {
+	var keys []string
+	for _, name := range ingress.GetBackendServiceNames(ing) {
+		keys = append(keys, ing.Namespace+"/"+name)
+	}
+	if ing.Spec.DefaultBackend == nil && defaultBackend != nil {
+		keys = append(keys, defaultBackend.Namespace+"/"+defaultBackend.Name)
+	}
+	return keys
+}
+
+// getIngressSecretKeys returns namespaced keys of ingress tls secrets
+// including secrets set by certificate annotations
+func getIngressSecretKeys(ing *networkv1.Ingress) []string {
+	var keys []string
+	seen := make(map[string]struct{})
+	add := func(name string) {
+		if name == "" {
+			return
+		}
+		key := ing.Namespace + "/" + name
+		if _, ok := seen[key]; ok {
+			return
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+
+	for _, tls := range ing.Spec.TLS {
+		add(tls.SecretName)
+	}
+	for k, v := range ing.Annotations {
+		if strings.HasPrefix(k, annotations.LBCertificatePrefix) {
+			add(v)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -18,3 +18,29 @@ func IsScIngress(i *v1.Ingress, class string) bool {
 
 	return false
 }
+
+// GetBackendServiceNames returns unique names of services used as ingress backends
+func GetBackendServiceNames(i *v1.Ingress) []string {
+	var names []string
+	seen := make(map[string]struct{})
+	if b := i.Spec.DefaultBackend; b != nil && b.Service != nil {
+		seen[b.Service.Name] = struct{}{}
+		names = append(names, b.Service.Name)
+	}
+	for _, rule := range i.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil {
+				continue
+			}
+			if _, ok := seen[path.Backend.Service.Name]; ok {
+				continue
+			}
+			seen[path.Backend.Service.Name] = struct{}{}
+			names = append(names, path.Backend.Service.Name)
+		}
+	}
+	return names
+}
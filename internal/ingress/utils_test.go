@@ -41,3 +41,37 @@ func TestIsScIngress(t *testing.T) {
 	ingressClassAnnotation.Annotations[IngressClassKey] = otherClass
 	g.Expect(IsScIngress(ingressClassAnnotation, defaultClass)).To(BeFalse())
 }
+
+func TestGetBackendServiceNames(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetBackendServiceNames(&v1.Ingress{})).To(BeEmpty())
+
+	backend := func(name string) v1.IngressBackend {
+		return v1.IngressBackend{Service: &v1.IngressServiceBackend{Name: name}}
+	}
+	ingress := &v1.Ingress{
+		Spec: v1.IngressSpec{
+			Rules: []v1.IngressRule{
+				{Host: "no-http.com"},
+				{
+					Host: "example.com",
+					IngressRuleValue: v1.IngressRuleValue{
+						HTTP: &v1.HTTPIngressRuleValue{
+							Paths: []v1.HTTPIngressPath{
+								{Path: "/a", Backend: backend("svc-a")},
+								{Path: "/b", Backend: backend("svc-b")},
+								{Path: "/c", Backend: backend("svc-a")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	g.Expect(GetBackendServiceNames(ingress)).To(Equal([]string{"svc-a", "svc-b"}))
+
+	defaultBackend := backend("svc-default")
+	ingress.Spec.DefaultBackend = &defaultBackend
+	g.Expect(GetBackendServiceNames(ingress)).To(Equal([]string{"svc-default", "svc-a", "svc-b"}))
+}
@@ -98,6 +98,20 @@ func (mr *MockLBManagerInterfaceMockRecorder) HasRegistration(name any) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HasRegistration", reflect.TypeOf((*MockLBManagerInterface)(nil).HasRegistration), name)
 }
 
+// LoadBalancerNames mocks base method.
+func (m *MockLBManagerInterface) LoadBalancerNames(ingress *v1.Ingress) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadBalancerNames", ingress)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// LoadBalancerNames indicates an expected call of LoadBalancerNames.
+func (mr *MockLBManagerInterfaceMockRecorder) LoadBalancerNames(ingress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadBalancerNames", reflect.TypeOf((*MockLBManagerInterface)(nil).LoadBalancerNames), ingress)
+}
+
 // NewLoadBalancer mocks base method.
 func (m *MockLBManagerInterface) NewLoadBalancer(input *serverscom.L7LoadBalancerCreateInput) (*serverscom.L7LoadBalancer, error, bool) {
 	m.ctrl.T.Helper()
@@ -114,6 +128,18 @@ func (mr *MockLBManagerInterfaceMockRecorder) NewLoadBalancer(input any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewLoadBalancer", reflect.TypeOf((*MockLBManagerInterface)(nil).NewLoadBalancer), input)
 }
 
+// TrackIngress mocks base method.
+func (m *MockLBManagerInterface) TrackIngress(ingress *v1.Ingress) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TrackIngress", ingress)
+}
+
+// TrackIngress indicates an expected call of TrackIngress.
+func (mr *MockLBManagerInterfaceMockRecorder) TrackIngress(ingress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrackIngress", reflect.TypeOf((*MockLBManagerInterface)(nil).TrackIngress), ingress)
+}
+
 // TranslateIngressToLB mocks base method.
 func (m *MockLBManagerInterface) TranslateIngressToLB(ingress *v1.Ingress, sslCerts map[string]string) (*serverscom.L7LoadBalancerCreateInput, error) {
 	m.ctrl.T.Helper()
@@ -57,6 +57,34 @@ func (mr *MockStorerMockRecorder) GetIngress(key any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIngress", reflect.TypeOf((*MockStorer)(nil).GetIngress), key)
 }
 
+// GetHostConflicts mocks base method.
+func (m *MockStorer) GetHostConflicts(ingress *v10.Ingress) []store.HostConflict {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHostConflicts", ingress)
+	ret0, _ := ret[0].([]store.HostConflict)
+	return ret0
+}
+
+// GetHostConflicts indicates an expected call of GetHostConflicts.
+func (mr *MockStorerMockRecorder) GetHostConflicts(ingress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHostConflicts", reflect.TypeOf((*MockStorer)(nil).GetHostConflicts), ingress)
+}
+
+// GetHostPathIngresses mocks base method.
+func (m *MockStorer) GetHostPathIngresses(ingress *v10.Ingress) []*v10.Ingress {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHostPathIngresses", ingress)
+	ret0, _ := ret[0].([]*v10.Ingress)
+	return ret0
+}
+
+// GetHostPathIngresses indicates an expected call of GetHostPathIngresses.
+func (mr *MockStorerMockRecorder) GetHostPathIngresses(ingress any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHostPathIngresses", reflect.TypeOf((*MockStorer)(nil).GetHostPathIngresses), ingress)
+}
+
 // GetIngressHostsInfo mocks base method.
 func (m *MockStorer) GetIngressHostsInfo(ingress *v10.Ingress) (map[string]store.HostInfo, error) {
 	m.ctrl.T.Helper()
@@ -72,6 +100,20 @@ func (mr *MockStorerMockRecorder) GetIngressHostsInfo(ingress any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIngressHostsInfo", reflect.TypeOf((*MockStorer)(nil).GetIngressHostsInfo), ingress)
 }
 
+// GetNodeWeights mocks base method.
+func (m *MockStorer) GetNodeWeights() map[string]int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeWeights")
+	ret0, _ := ret[0].(map[string]int)
+	return ret0
+}
+
+// GetNodeWeights indicates an expected call of GetNodeWeights.
+func (mr *MockStorerMockRecorder) GetNodeWeights() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeWeights", reflect.TypeOf((*MockStorer)(nil).GetNodeWeights))
+}
+
 // GetNodesIpList mocks base method.
 func (m *MockStorer) GetNodesIpList() []string {
 	m.ctrl.T.Helper()
@@ -116,6 +158,20 @@ func (mr *MockStorerMockRecorder) GetService(key any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetService", reflect.TypeOf((*MockStorer)(nil).GetService), key)
 }
 
+// GetServicePods mocks base method.
+func (m *MockStorer) GetServicePods(svc *v1.Service) []*v1.Pod {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetServicePods", svc)
+	ret0, _ := ret[0].([]*v1.Pod)
+	return ret0
+}
+
+// GetServicePods indicates an expected call of GetServicePods.
+func (mr *MockStorerMockRecorder) GetServicePods(svc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetServicePods", reflect.TypeOf((*MockStorer)(nil).GetServicePods), svc)
+}
+
 // ListIngress mocks base method.
 func (m *MockStorer) ListIngress() []*v10.Ingress {
 	m.ctrl.T.Helper()
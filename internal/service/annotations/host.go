@@ -0,0 +1,173 @@
+package annotations
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// VHostPrefix is a prefix of host scoped ingress annotations: servers.com/vhost.<host>.<setting>.
+	// Name part of annotation key is limited to 63 characters, so <host> could be replaced with a short name
+	// of hosts listed in servers.com/vhost.<name>.hosts annotation.
+	VHostPrefix = "servers.com/vhost."
+
+	// VHostHosts is a setting with comma separated hosts the settings of a name apply to
+	VHostHosts = "hosts"
+
+	VHostHTTP2                 = "http2"
+	VHostRealIPHeader          = "real-ip-header"
+	VHostRealIPTrustedNetworks = "real-ip-trusted-networks"
+	VHostListenPorts           = "listen-ports"
+	VHostWhitelistSourceRange  = "whitelist-source-range"
+)
+
+// vhostSettings maps host scoped setting to service annotation with the same meaning
+var vhostSettings = map[string]string{
+	VHostHTTP2:                 AppProtocol,
+	VHostRealIPHeader:          LBIPHeader,
+	VHostRealIPTrustedNetworks: LBIPSubnets,
+}
+
+// vhostValidators contains validators for host scoped settings
+var vhostValidators = map[string]validator{
+	VHostHTTP2:                 func(v string) error { _, err := ParseBool(v); return err },
+	VHostRealIPHeader:          serviceValidators[LBIPHeader],
+	VHostRealIPTrustedNetworks: serviceValidators[LBIPSubnets],
+	VHostListenPorts:           ingressValidators[ListenPorts],
+	VHostWhitelistSourceRange:  ingressValidators[WhitelistSourceRange],
+	VHostHosts:                 func(v string) error { _, err := ParseHostList(v); return err },
+}
+
+// VHostAnnotation returns host scoped annotation key for host and setting
+func VHostAnnotation(host, setting string) string {
+	return VHostPrefix + host + "." + setting
+}
+
+// ParseVHostAnnotation splits suffix of host scoped annotation into host and setting
+func ParseVHostAnnotation(suffix string) (string, string, bool) {
+	i := strings.LastIndex(suffix, ".")
+	if i <= 0 || i == len(suffix)-1 {
+		return "", "", false
+	}
+	return suffix[:i], suffix[i+1:], true
+}
+
+// ParseHostList parses comma separated list of hosts, wildcard hosts are allowed
+func ParseHostList(input string) ([]string, error) {
+	var hosts []string
+	for _, host := range strings.Split(input, ",") {
+		host = strings.TrimSpace(host)
+		errs := validation.IsDNS1123Subdomain(host)
+		if strings.HasPrefix(host, "*.") {
+			errs = validation.IsWildcardDNS1123Subdomain(host)
+		}
+		if len(errs) != 0 {
+			return nil, fmt.Errorf("invalid host %q: %s", host, strings.Join(errs, ", "))
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// getVHostSetting returns host scoped setting of host. Annotation with host in the key takes precedence
+// over annotations of names listing host in their hosts setting, names are checked in sorted order.
+func getVHostSetting(host, setting string, annotations map[string]string) (string, bool) {
+	if value, ok := annotations[VHostAnnotation(host, setting)]; ok {
+		return value, true
+	}
+
+	var names []string
+	for k, v := range annotations {
+		suffix, ok := strings.CutPrefix(k, VHostPrefix)
+		if !ok {
+			continue
+		}
+		name, s, ok := ParseVHostAnnotation(suffix)
+		if !ok || s != VHostHosts {
+			continue
+		}
+		if hosts, err := ParseHostList(v); err == nil && slices.Contains(hosts, host) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if value, ok := annotations[VHostAnnotation(name, setting)]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// GetHostVHostAnnotations returns host scoped ingress annotations of host
+// converted to service annotations, so they could be applied with FillLBVHostZoneWithServiceAnnotations.
+// Invalid annotation values are skipped, they are reported by ValidateIngressAnnotations.
+func GetHostVHostAnnotations(host string, annotations map[string]string) map[string]string {
+	res := make(map[string]string)
+	for setting, key := range vhostSettings {
+		v, ok := getVHostSetting(host, setting, annotations)
+		if !ok || vhostValidators[setting](v) != nil {
+			continue
+		}
+		if setting == VHostHTTP2 {
+			enabled, _ := ParseBool(v)
+			v = "http"
+			if enabled {
+				v = "http2"
+			}
+		}
+		res[key] = v
+	}
+	return res
+}
+
+// GetListenPorts returns listen ports of host vhost, host scoped annotation takes precedence
+// over ingress level one. Returns nil if ports aren't set or invalid.
+func GetListenPorts(host string, annotations map[string]string) []int32 {
+	value, ok := getVHostSetting(host, VHostListenPorts, annotations)
+	if !ok {
+		value, ok = annotations[ListenPorts]
+	}
+	if !ok {
+		return nil
+	}
+	ports, err := ParsePortList(value)
+	if err != nil {
+		return nil
+	}
+	return ports
+}
+
+// GetWhitelistSourceRange returns client source ranges allowed to reach host, host scoped annotation
+// takes precedence over ingress level one. Returns false if ranges aren't set and an error if they are invalid.
+func GetWhitelistSourceRange(host string, annotations map[string]string) ([]string, bool, error) {
+	value, ok := getVHostSetting(host, VHostWhitelistSourceRange, annotations)
+	if !ok {
+		value, ok = annotations[WhitelistSourceRange]
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	ranges, err := ParseCIDRList(value)
+	return ranges, true, err
+}
+
+// validateVHostAnnotation validates host scoped annotation by its suffix
+func validateVHostAnnotation(suffix, value string) error {
+	host, setting, ok := ParseVHostAnnotation(suffix)
+	if !ok {
+		return fmt.Errorf("must be in %s<host>.<setting> format", VHostPrefix)
+	}
+	if errs := validation.IsDNS1123Subdomain(host); len(errs) != 0 {
+		return fmt.Errorf("invalid host %q: %s", host, strings.Join(errs, ", "))
+	}
+	validate, ok := vhostValidators[setting]
+	if !ok {
+		return fmt.Errorf("unknown setting %q, must be one of %s", setting, strings.Join([]string{VHostHTTP2, VHostRealIPHeader, VHostRealIPTrustedNetworks, VHostListenPorts, VHostWhitelistSourceRange, VHostHosts}, ", "))
+	}
+	return validate(value)
+}
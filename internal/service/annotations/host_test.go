@@ -0,0 +1,144 @@
+package annotations
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseVHostAnnotation(t *testing.T) {
+	g := NewWithT(t)
+
+	host, setting, ok := ParseVHostAnnotation("example.com.http2")
+	g.Expect(ok).To(BeTrue())
+	g.Expect(host).To(Equal("example.com"))
+	g.Expect(setting).To(Equal("http2"))
+
+	for _, suffix := range []string{"http2", ".http2", "example.com."} {
+		_, _, ok = ParseVHostAnnotation(suffix)
+		g.Expect(ok).To(BeFalse(), suffix)
+	}
+}
+
+func TestGetHostVHostAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	annotations := map[string]string{
+		VHostAnnotation("example.com", VHostHTTP2):                 "true",
+		VHostAnnotation("example.com", VHostRealIPHeader):          "forwarded_for",
+		VHostAnnotation("example.com", VHostRealIPTrustedNetworks): "10.0.0.0/8",
+		VHostAnnotation("foo.com", VHostHTTP2):                     "false",
+		VHostAnnotation("bar.com", VHostHTTP2):                     "invalid",
+		VHostAnnotation("bar.com", "unknown"):                      "value",
+		LBGeoIPEnabled:                                             "true",
+	}
+
+	g.Expect(GetHostVHostAnnotations("example.com", annotations)).To(Equal(map[string]string{
+		AppProtocol: "http2",
+		LBIPHeader:  "forwarded_for",
+		LBIPSubnets: "10.0.0.0/8",
+	}))
+	g.Expect(GetHostVHostAnnotations("foo.com", annotations)).To(Equal(map[string]string{
+		AppProtocol: "http",
+	}))
+	g.Expect(GetHostVHostAnnotations("bar.com", annotations)).To(BeEmpty())
+	g.Expect(GetHostVHostAnnotations("other.com", annotations)).To(BeEmpty())
+
+	t.Run("Settings of hosts listed under a name", func(t *testing.T) {
+		g := NewWithT(t)
+
+		long := "a-very-long-host-name-which-does-not-fit-into-annotation-key.example.com"
+		annotations := map[string]string{
+			VHostAnnotation("web", VHostHosts):                long + ", example.com",
+			VHostAnnotation("web", VHostHTTP2):                "true",
+			VHostAnnotation("web", VHostRealIPHeader):         "real_ip",
+			VHostAnnotation("example.com", VHostRealIPHeader): "forwarded_for",
+		}
+
+		g.Expect(GetHostVHostAnnotations(long, annotations)).To(Equal(map[string]string{
+			AppProtocol: "http2",
+			LBIPHeader:  "real_ip",
+		}))
+		// host in the key takes precedence
+		g.Expect(GetHostVHostAnnotations("example.com", annotations)).To(Equal(map[string]string{
+			AppProtocol: "http2",
+			LBIPHeader:  "forwarded_for",
+		}))
+		g.Expect(GetHostVHostAnnotations("foo.com", annotations)).To(BeEmpty())
+	})
+}
+
+func TestValidateVHostAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ValidateIngressAnnotations(map[string]string{
+		VHostAnnotation("example.com", VHostHTTP2):                 "true",
+		VHostAnnotation("example.com", VHostRealIPHeader):          "real_ip",
+		VHostAnnotation("example.com", VHostRealIPTrustedNetworks): "10.0.0.0/8",
+		VHostAnnotation("example.com", VHostListenPorts):           "80,8080",
+		VHostAnnotation("example.com", VHostWhitelistSourceRange):  "10.0.0.0/8",
+		VHostAnnotation("web", VHostHosts):                         "example.com, *.example.com",
+		VHostAnnotation("web", VHostHTTP2):                         "true",
+	})).To(Succeed())
+
+	err := ValidateIngressAnnotations(map[string]string{
+		VHostAnnotation("example.com", VHostHTTP2):        "yes",
+		VHostAnnotation("example.com", "http3"):           "true",
+		VHostAnnotation("Example_com", VHostRealIPHeader): "real_ip",
+		VHostPrefix + "http2":                             "true",
+		VHostAnnotation("example.com", VHostListenPorts):  "8080,8080",
+		VHostAnnotation("web", VHostHosts):                "example.com,Foo_com",
+	})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/vhost.example.com.http2]: Invalid value: "yes": must be a boolean`))
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/vhost.example.com.http3]: Invalid value: "true": unknown setting "http3"`))
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/vhost.Example_com.real-ip-header]: Invalid value: "real_ip": invalid host "Example_com"`))
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/vhost.example.com.listen-ports]: Invalid value: "8080,8080": duplicate port 8080`))
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/vhost.http2]: Invalid value: "true": must be in servers.com/vhost.<host>.<setting> format`))
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/vhost.web.hosts]: Invalid value: "example.com,Foo_com": invalid host "Foo_com"`))
+}
+
+func TestGetWhitelistSourceRange(t *testing.T) {
+	g := NewWithT(t)
+
+	_, ok, err := GetWhitelistSourceRange("example.com", map[string]string{})
+	g.Expect(ok).To(BeFalse())
+	g.Expect(err).To(BeNil())
+
+	annotations := map[string]string{WhitelistSourceRange: "10.0.0.0/8"}
+	ranges, ok, err := GetWhitelistSourceRange("example.com", annotations)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(err).To(BeNil())
+	g.Expect(ranges).To(Equal([]string{"10.0.0.0/8"}))
+
+	annotations[VHostAnnotation("example.com", VHostWhitelistSourceRange)] = "192.168.0.0/16, 172.16.0.0/12"
+	ranges, _, _ = GetWhitelistSourceRange("example.com", annotations)
+	g.Expect(ranges).To(Equal([]string{"192.168.0.0/16", "172.16.0.0/12"}))
+	ranges, _, _ = GetWhitelistSourceRange("foo.com", annotations)
+	g.Expect(ranges).To(Equal([]string{"10.0.0.0/8"}))
+
+	annotations[WhitelistSourceRange] = "10.0.0.0"
+	_, ok, err = GetWhitelistSourceRange("foo.com", annotations)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestGetListenPorts(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetListenPorts("example.com", map[string]string{})).To(BeNil())
+
+	annotations := map[string]string{ListenPorts: "80,8080"}
+	g.Expect(GetListenPorts("example.com", annotations)).To(Equal([]int32{80, 8080}))
+
+	annotations[VHostAnnotation("example.com", VHostListenPorts)] = "443,8443"
+	g.Expect(GetListenPorts("example.com", annotations)).To(Equal([]int32{443, 8443}))
+	g.Expect(GetListenPorts("foo.com", annotations)).To(Equal([]int32{80, 8080}))
+
+	annotations[VHostAnnotation("web", VHostHosts)] = "foo.com"
+	annotations[VHostAnnotation("web", VHostListenPorts)] = "8081"
+	g.Expect(GetListenPorts("foo.com", annotations)).To(Equal([]int32{8081}))
+
+	annotations[VHostAnnotation("example.com", VHostListenPorts)] = "invalid"
+	g.Expect(GetListenPorts("example.com", annotations)).To(BeNil())
+}
@@ -7,23 +7,37 @@ import (
 )
 
 const (
+	LBStoreLogs             = "servers.com/load-balancer-store-logs"
 	LBStoreLogsRegionCode   = "servers.com/load-balancer-store-logs-region-code"
 	LBGeoIPEnabled          = "servers.com/load-balancer-geo-ip-enabled"
-	LBRealIPTrustedNetworks = "servers.com/load-balancer-real-ip-trusted-networks" // TODO not implemented yet
+	LBRealIPHeader          = "servers.com/load-balancer-real-ip-header"
+	LBRealIPTrustedNetworks = "servers.com/load-balancer-real-ip-trusted-networks"
 	LBMinTLSVersion         = "servers.com/load-balancer-min-tls-version"
 	LBClusterID             = "servers.com/cluster-id"
+	LBGroup                 = "servers.com/load-balancer-group"
+	LBID                    = "servers.com/load-balancer-id"
+	LBAdopt                 = "servers.com/load-balancer-adopt"
+	LBReclaimPolicy         = "servers.com/load-balancer-reclaim-policy"
+	LBName                  = "servers.com/load-balancer-name"
+	SSLRedirect             = "servers.com/ssl-redirect"
+	ListenPorts             = "servers.com/listen-ports"
+	Canary                  = "servers.com/canary"
+	CanaryWeight            = "servers.com/canary-weight"
+	WhitelistSourceRange    = "servers.com/whitelist-source-range"
+
+	// ReclaimPolicyRetain keeps load balancer in portal when ingress is removed
+	ReclaimPolicyRetain = "Retain"
+	// ReclaimPolicyDelete deletes load balancer from portal when ingress is removed
+	ReclaimPolicyDelete = "Delete"
+
+	// StoreLogsOff is a value of LBStoreLogsRegionCode annotation which disables access logs
+	StoreLogsOff = "off"
 )
 
 // FillLBWithIngressAnnotations prepares the LB input based on annotations.
 func FillLBWithIngressAnnotations(lbInput *serverscom.L7LoadBalancerCreateInput, annotations map[string]string) (*serverscom.L7LoadBalancerCreateInput, error) {
-	// LBStoreLogsRegionCode annotation
-	if value, ok := annotations[LBStoreLogsRegionCode]; ok {
-		if regionID, found := GetStorageRegionIDByCode(value); found {
-			lbInput.StoreLogsRegionID = &regionID
-			storeLogs := true
-			lbInput.StoreLogs = &storeLogs
-		}
-	}
+	// LBStoreLogs & LBStoreLogsRegionCode annotations
+	lbInput.StoreLogs, lbInput.StoreLogsRegionID = GetStoreLogs(annotations)
 
 	// LBGeoIPEnabled annotation
 	if value, ok := annotations[LBGeoIPEnabled]; ok {
@@ -36,11 +50,16 @@ func FillLBWithIngressAnnotations(lbInput *serverscom.L7LoadBalancerCreateInput,
 
 	// LBMinTLSVersion annotation
 	if value, ok := annotations[LBMinTLSVersion]; ok {
-		for i := range lbInput.UpstreamZones {
-			lbInput.UpstreamZones[i].TLSPreset = &value
+		if _, err := ParseOneOf(value, TLSVersions); err == nil {
+			for i := range lbInput.UpstreamZones {
+				lbInput.UpstreamZones[i].TLSPreset = &value
+			}
 		}
 	}
 
+	// LBRealIPHeader & LBRealIPTrustedNetworks annotations
+	fillVHostZonesWithRealIP(lbInput.VHostZones, annotations)
+
 	// LBClusterID annotation
 	if id, ok := annotations[LBClusterID]; ok && id != "" {
 		lbInput.ClusterID = &id
@@ -48,3 +67,130 @@ func FillLBWithIngressAnnotations(lbInput *serverscom.L7LoadBalancerCreateInput,
 
 	return lbInput, nil
 }
+
+// GetStoreLogs returns access logs settings of load balancer. Logs are enabled by a region code
+// and disabled by "false" LBStoreLogs or "off" LBStoreLogsRegionCode annotation.
+// Returns nils if logs aren't configured, so settings of load balancer in portal are kept.
+// Invalid values are ignored, they are reported by ValidateIngressAnnotations.
+func GetStoreLogs(annotations map[string]string) (*bool, *int) {
+	enabled := true
+	if value, ok := annotations[LBStoreLogs]; ok {
+		if val, err := ParseBool(value); err == nil {
+			enabled = val
+		}
+	}
+	region, hasRegion := annotations[LBStoreLogsRegionCode]
+	if !enabled || (hasRegion && region == StoreLogsOff) {
+		disabled := false
+		return &disabled, nil
+	}
+	if !hasRegion {
+		return nil, nil
+	}
+	regionID, err := ParseRegionCode(region)
+	if err != nil {
+		return nil, nil
+	}
+	return &enabled, &regionID
+}
+
+// GetLBGroup returns load balancer group of ingress or empty string if ingress isn't grouped.
+// Invalid group is ignored, it's reported by ValidateIngressAnnotations.
+func GetLBGroup(annotations map[string]string) string {
+	group := annotations[LBGroup]
+	if validateDNSLabel(group) != nil {
+		return ""
+	}
+	return group
+}
+
+// GetSSLRedirect returns whether HTTP requests to TLS hosts are redirected to HTTPS,
+// defaultValue is returned if annotation isn't set or invalid.
+func GetSSLRedirect(annotations map[string]string, defaultValue bool) bool {
+	value, ok := annotations[SSLRedirect]
+	if !ok {
+		return defaultValue
+	}
+	redirect, err := ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return redirect
+}
+
+// IsCanary returns true if ingress is a canary companion of ingresses with the same hosts and paths
+func IsCanary(annotations map[string]string) bool {
+	canary, _ := ParseBool(annotations[Canary])
+	return canary
+}
+
+// GetCanaryWeight returns percent of traffic sent to canary ingress backends, 0 if weight isn't set or invalid
+func GetCanaryWeight(annotations map[string]string) int {
+	weight, err := ParseIntInRange(annotations[CanaryWeight], 0, 100)
+	if err != nil {
+		return 0
+	}
+	return weight
+}
+
+// GetLBName returns load balancer name set for ingress or empty string if it isn't set or invalid.
+func GetLBName(annotations map[string]string) string {
+	name := annotations[LBName]
+	if validateDNSLabel(name) != nil {
+		return ""
+	}
+	return name
+}
+
+// GetLBAdoption returns id of an existing load balancer which ingress adopts
+// and whether adoption of a balancer without ownership label is confirmed.
+// Empty id means ingress doesn't adopt a balancer.
+func GetLBAdoption(annotations map[string]string) (string, bool) {
+	id := annotations[LBID]
+	confirmed, _ := ParseBool(annotations[LBAdopt])
+	return id, confirmed
+}
+
+// GetLBReclaimPolicy returns reclaim policy of ingress load balancer
+// or empty string if policy isn't set or invalid.
+func GetLBReclaimPolicy(annotations map[string]string) string {
+	policy, err := ParseOneOf(annotations[LBReclaimPolicy], ReclaimPolicies)
+	if err != nil {
+		return ""
+	}
+	return policy
+}
+
+// fillVHostZonesWithRealIP applies ingress level real ip settings to every vhost zone.
+// Ingress level values override values from service annotations:
+//   - LBRealIPHeader sets header name for all vhosts
+//   - LBRealIPTrustedNetworks replaces trusted networks of all vhosts with a header,
+//     so it takes effect only with LBRealIPHeader or service level LBIPHeader
+func fillVHostZonesWithRealIP(vhostZones []serverscom.L7VHostZoneInput, annotations map[string]string) {
+	var header string
+	if value, ok := annotations[LBRealIPHeader]; ok {
+		if name, err := ParseOneOfFold(value, RealIPHeaderNames); err == nil {
+			header = name
+		}
+	}
+
+	var networks []string
+	if value, ok := annotations[LBRealIPTrustedNetworks]; ok {
+		if n, err := ParseCIDRList(value); err == nil {
+			networks = n
+		}
+	}
+
+	for i := range vhostZones {
+		vz := &vhostZones[i]
+		if header != "" {
+			if vz.RealIPHeader == nil {
+				vz.RealIPHeader = new(serverscom.RealIPHeader)
+			}
+			vz.RealIPHeader.Name = serverscom.RealIPHeaderName(header)
+		}
+		if networks != nil && vz.RealIPHeader != nil {
+			vz.RealIPHeader.Networks = networks
+		}
+	}
+}
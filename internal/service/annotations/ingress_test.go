@@ -56,3 +56,195 @@ func TestFillLBWithIngressAnnotations(t *testing.T) {
 		}
 	})
 }
+
+func TestGetStoreLogs(t *testing.T) {
+	enabled, disabled, us01 := true, false, 1
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		storeLogs   *bool
+		regionID    *int
+	}{
+		{
+			name: "Not configured",
+		},
+		{
+			name:        "Region enables logs",
+			annotations: map[string]string{LBStoreLogsRegionCode: "US01"},
+			storeLogs:   &enabled,
+			regionID:    &us01,
+		},
+		{
+			name:        "Enabled with region",
+			annotations: map[string]string{LBStoreLogs: "true", LBStoreLogsRegionCode: "US01"},
+			storeLogs:   &enabled,
+			regionID:    &us01,
+		},
+		{
+			name:        "Disabled with region",
+			annotations: map[string]string{LBStoreLogs: "false", LBStoreLogsRegionCode: "US01"},
+			storeLogs:   &disabled,
+		},
+		{
+			name:        "Disabled",
+			annotations: map[string]string{LBStoreLogs: "false"},
+			storeLogs:   &disabled,
+		},
+		{
+			name:        "Region off",
+			annotations: map[string]string{LBStoreLogsRegionCode: StoreLogsOff},
+			storeLogs:   &disabled,
+		},
+		{
+			name:        "Enabled without region",
+			annotations: map[string]string{LBStoreLogs: "true"},
+		},
+		{
+			name:        "Invalid value is ignored",
+			annotations: map[string]string{LBStoreLogs: "maybe", LBStoreLogsRegionCode: "US01"},
+			storeLogs:   &enabled,
+			regionID:    &us01,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			storeLogs, regionID := GetStoreLogs(tc.annotations)
+			g.Expect(storeLogs).To(Equal(tc.storeLogs))
+			g.Expect(regionID).To(Equal(tc.regionID))
+		})
+	}
+}
+
+func TestFillLBWithIngressRealIPAnnotations(t *testing.T) {
+	newLBInput := func() *serverscom.L7LoadBalancerCreateInput {
+		return &serverscom.L7LoadBalancerCreateInput{
+			VHostZones: []serverscom.L7VHostZoneInput{
+				{ID: "without-header"},
+				{ID: "with-header", RealIPHeader: &serverscom.RealIPHeader{
+					Name:     serverscom.ForwardedFor,
+					Networks: []string{"192.168.0.0/16"},
+				}},
+			},
+		}
+	}
+
+	t.Run("Service level values are kept without ingress annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		result, err := FillLBWithIngressAnnotations(newLBInput(), map[string]string{})
+		g.Expect(err).To(BeNil())
+		g.Expect(result.VHostZones[0].RealIPHeader).To(BeNil())
+		g.Expect(result.VHostZones[1].RealIPHeader.Name).To(Equal(serverscom.ForwardedFor))
+		g.Expect(result.VHostZones[1].RealIPHeader.Networks).To(Equal([]string{"192.168.0.0/16"}))
+	})
+
+	t.Run("Ingress header applies to every vhost and overrides service header", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBRealIPHeader: "real_ip",
+		}
+		result, err := FillLBWithIngressAnnotations(newLBInput(), annotations)
+		g.Expect(err).To(BeNil())
+		g.Expect(result.VHostZones[0].RealIPHeader.Name).To(Equal(serverscom.RealIP))
+		g.Expect(result.VHostZones[0].RealIPHeader.Networks).To(BeEmpty())
+		g.Expect(result.VHostZones[1].RealIPHeader.Name).To(Equal(serverscom.RealIP))
+		g.Expect(result.VHostZones[1].RealIPHeader.Networks).To(Equal([]string{"192.168.0.0/16"}))
+	})
+
+	t.Run("Ingress trusted networks override service networks", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBRealIPTrustedNetworks: "10.0.0.0/8, 172.16.0.0/12",
+		}
+		result, err := FillLBWithIngressAnnotations(newLBInput(), annotations)
+		g.Expect(err).To(BeNil())
+		g.Expect(result.VHostZones[0].RealIPHeader).To(BeNil())
+		g.Expect(result.VHostZones[1].RealIPHeader.Name).To(Equal(serverscom.ForwardedFor))
+		g.Expect(result.VHostZones[1].RealIPHeader.Networks).To(Equal([]string{"10.0.0.0/8", "172.16.0.0/12"}))
+	})
+
+	t.Run("Ingress header and trusted networks", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBRealIPHeader:          "forwarded_for",
+			LBRealIPTrustedNetworks: "10.0.0.0/8",
+		}
+		result, err := FillLBWithIngressAnnotations(newLBInput(), annotations)
+		g.Expect(err).To(BeNil())
+		for _, vz := range result.VHostZones {
+			g.Expect(vz.RealIPHeader.Name).To(Equal(serverscom.ForwardedFor))
+			g.Expect(vz.RealIPHeader.Networks).To(Equal([]string{"10.0.0.0/8"}))
+		}
+	})
+
+	t.Run("Invalid values are skipped", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBRealIPHeader:          "x-real-ip",
+			LBRealIPTrustedNetworks: "10.0.0.0",
+		}
+		result, err := FillLBWithIngressAnnotations(newLBInput(), annotations)
+		g.Expect(err).To(BeNil())
+		g.Expect(result.VHostZones[0].RealIPHeader).To(BeNil())
+		g.Expect(result.VHostZones[1].RealIPHeader.Name).To(Equal(serverscom.ForwardedFor))
+		g.Expect(result.VHostZones[1].RealIPHeader.Networks).To(Equal([]string{"192.168.0.0/16"}))
+	})
+}
+
+func TestGetLBAdoption(t *testing.T) {
+	g := NewWithT(t)
+
+	id, confirmed := GetLBAdoption(map[string]string{})
+	g.Expect(id).To(BeEmpty())
+	g.Expect(confirmed).To(BeFalse())
+
+	id, confirmed = GetLBAdoption(map[string]string{LBID: "lb-id"})
+	g.Expect(id).To(Equal("lb-id"))
+	g.Expect(confirmed).To(BeFalse())
+
+	id, confirmed = GetLBAdoption(map[string]string{LBID: "lb-id", LBAdopt: "true"})
+	g.Expect(id).To(Equal("lb-id"))
+	g.Expect(confirmed).To(BeTrue())
+
+	_, confirmed = GetLBAdoption(map[string]string{LBID: "lb-id", LBAdopt: "invalid"})
+	g.Expect(confirmed).To(BeFalse())
+}
+
+func TestGetLBReclaimPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetLBReclaimPolicy(map[string]string{})).To(BeEmpty())
+	g.Expect(GetLBReclaimPolicy(map[string]string{LBReclaimPolicy: "Retain"})).To(Equal(ReclaimPolicyRetain))
+	g.Expect(GetLBReclaimPolicy(map[string]string{LBReclaimPolicy: "Delete"})).To(Equal(ReclaimPolicyDelete))
+	g.Expect(GetLBReclaimPolicy(map[string]string{LBReclaimPolicy: "Keep"})).To(BeEmpty())
+}
+
+func TestGetLBName(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetLBName(map[string]string{})).To(BeEmpty())
+	g.Expect(GetLBName(map[string]string{LBName: "shop-frontend"})).To(Equal("shop-frontend"))
+	g.Expect(GetLBName(map[string]string{LBName: "Shop.Frontend"})).To(BeEmpty())
+}
+
+func TestGetSSLRedirect(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetSSLRedirect(map[string]string{}, false)).To(BeFalse())
+	g.Expect(GetSSLRedirect(map[string]string{}, true)).To(BeTrue())
+	g.Expect(GetSSLRedirect(map[string]string{SSLRedirect: "true"}, false)).To(BeTrue())
+	g.Expect(GetSSLRedirect(map[string]string{SSLRedirect: "false"}, true)).To(BeFalse())
+	g.Expect(GetSSLRedirect(map[string]string{SSLRedirect: "invalid"}, true)).To(BeTrue())
+}
+
+func TestCanaryAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(IsCanary(map[string]string{})).To(BeFalse())
+	g.Expect(IsCanary(map[string]string{Canary: "true"})).To(BeTrue())
+	g.Expect(IsCanary(map[string]string{Canary: "invalid"})).To(BeFalse())
+
+	g.Expect(GetCanaryWeight(map[string]string{})).To(Equal(0))
+	g.Expect(GetCanaryWeight(map[string]string{CanaryWeight: "30"})).To(Equal(30))
+	g.Expect(GetCanaryWeight(map[string]string{CanaryWeight: "101"})).To(Equal(0))
+}
@@ -0,0 +1,113 @@
+package annotations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VHostServiceAnnotations contains service annotations which configure a vhost zone.
+// Several services could serve one host, so values of these annotations are merged.
+//
+// Vhost settings are applied with the following precedence, from highest to lowest:
+//   - ingress annotations, applied to every vhost of the balancer
+//   - host scoped ingress annotations, see VHostPrefix
+//   - service annotations merged from all services of the host
+var VHostServiceAnnotations = []string{
+	AppProtocol,
+	LBIPHeader,
+	LBIPSubnets,
+}
+
+// Conflict describes different values of one annotation on services of the same host
+type Conflict struct {
+	Key string
+	// Values maps service name to annotation value
+	Values map[string]string
+}
+
+// Error implements the error interface.
+func (c Conflict) Error() string {
+	services := make([]string, 0, len(c.Values))
+	for svc := range c.Values {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+
+	values := make([]string, 0, len(services))
+	for _, svc := range services {
+		values = append(values, fmt.Sprintf("%s=%q", svc, c.Values[svc]))
+	}
+	return fmt.Sprintf("annotation %s has conflicting values on services: %s", c.Key, strings.Join(values, ", "))
+}
+
+// MergeVHostAnnotations merges vhost annotations of services serving the same host.
+// servicesAnnotations maps service name to its annotations.
+// Annotation which has different values on services isn't merged and reported as a conflict,
+// services without annotation don't conflict with others.
+//
+// App protocols are compared ignoring case.
+// LBIPHeader and LBIPSubnets are one setting, they are merged together and reported as a conflict
+// of LBIPHeader. Header names are compared ignoring case, subnets without a header are ignored.
+func MergeVHostAnnotations(servicesAnnotations map[string]map[string]string) (map[string]string, []Conflict) {
+	merged := make(map[string]string)
+	var conflicts []Conflict
+
+	appProtocols := make(map[string]string)
+	realIPs := make(map[string]string)
+	for svc, annotations := range servicesAnnotations {
+		if value, ok := annotations[AppProtocol]; ok {
+			appProtocols[svc] = strings.ToLower(value)
+		}
+		if value, ok := annotations[LBIPHeader]; ok {
+			realIPs[svc] = realIPSetting(value, annotations[LBIPSubnets])
+		}
+	}
+
+	if value, ok := mergeValues(appProtocols); ok {
+		merged[AppProtocol] = value
+	} else if len(appProtocols) > 0 {
+		conflicts = append(conflicts, Conflict{Key: AppProtocol, Values: appProtocols})
+	}
+
+	if value, ok := mergeValues(realIPs); ok {
+		header, subnets, _ := strings.Cut(value, realIPSubnetsSep)
+		merged[LBIPHeader] = header
+		if subnets != "" {
+			merged[LBIPSubnets] = subnets
+		}
+	} else if len(realIPs) > 0 {
+		conflicts = append(conflicts, Conflict{Key: LBIPHeader, Values: realIPs})
+	}
+
+	return merged, conflicts
+}
+
+// realIPSubnetsSep separates header and subnets in a merged real ip setting
+const realIPSubnetsSep = " with "
+
+// realIPSetting returns real ip header and subnets of service as one comparable value
+func realIPSetting(header, subnets string) string {
+	value := strings.ToLower(header)
+	if subnets == "" {
+		return value
+	}
+	if s, err := ParseCIDRList(subnets); err == nil {
+		sort.Strings(s)
+		subnets = strings.Join(s, ",")
+	}
+	return value + realIPSubnetsSep + subnets
+}
+
+// mergeValues returns the value if there are values and all of them are the same
+func mergeValues(values map[string]string) (string, bool) {
+	var merged string
+	found := false
+	for _, value := range values {
+		if found && value != merged {
+			return "", false
+		}
+		merged, found = value, true
+	}
+	return merged, found
+}
@@ -0,0 +1,70 @@
+package annotations
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMergeVHostAnnotations(t *testing.T) {
+	t.Run("No conflicts", func(t *testing.T) {
+		g := NewWithT(t)
+		merged, conflicts := MergeVHostAnnotations(map[string]map[string]string{
+			"svc-a": {AppProtocol: "http2", LBIPHeader: "real_ip", LBBalancingAlgorithm: "round_robin"},
+			"svc-b": {AppProtocol: "http2", LBIPSubnets: "10.0.0.0/8"},
+			"svc-c": nil,
+		})
+		g.Expect(conflicts).To(BeEmpty())
+		g.Expect(merged).To(Equal(map[string]string{
+			AppProtocol: "http2",
+			LBIPHeader:  "real_ip",
+		}))
+	})
+
+	t.Run("Real ip header and subnets are merged together", func(t *testing.T) {
+		g := NewWithT(t)
+		merged, conflicts := MergeVHostAnnotations(map[string]map[string]string{
+			"svc-a": {LBIPHeader: "real_ip", LBIPSubnets: "10.0.0.0/8, 192.168.0.0/16"},
+			"svc-b": {LBIPHeader: "Real_IP", LBIPSubnets: "192.168.0.0/16,10.0.0.0/8"},
+		})
+		g.Expect(conflicts).To(BeEmpty())
+		g.Expect(merged).To(Equal(map[string]string{
+			LBIPHeader:  "real_ip",
+			LBIPSubnets: "10.0.0.0/8,192.168.0.0/16",
+		}))
+	})
+
+	t.Run("App protocols are compared ignoring case", func(t *testing.T) {
+		g := NewWithT(t)
+		merged, conflicts := MergeVHostAnnotations(map[string]map[string]string{
+			"svc-a": {AppProtocol: "http2"},
+			"svc-b": {AppProtocol: "HTTP2"},
+		})
+		g.Expect(conflicts).To(BeEmpty())
+		g.Expect(merged).To(Equal(map[string]string{AppProtocol: "http2"}))
+	})
+
+	t.Run("Real ip subnets conflict", func(t *testing.T) {
+		g := NewWithT(t)
+		merged, conflicts := MergeVHostAnnotations(map[string]map[string]string{
+			"svc-a": {LBIPHeader: "real_ip", LBIPSubnets: "10.0.0.0/8"},
+			"svc-b": {LBIPHeader: "real_ip"},
+		})
+		g.Expect(merged).To(BeEmpty())
+		g.Expect(conflicts).To(HaveLen(1))
+		g.Expect(conflicts[0].Key).To(Equal(LBIPHeader))
+		g.Expect(conflicts[0].Error()).To(Equal(`annotation servers.com/load-balancer-ip-header has conflicting values on services: svc-a="real_ip with 10.0.0.0/8", svc-b="real_ip"`))
+	})
+
+	t.Run("Conflicting values are not merged", func(t *testing.T) {
+		g := NewWithT(t)
+		merged, conflicts := MergeVHostAnnotations(map[string]map[string]string{
+			"svc-a": {AppProtocol: "http2", LBIPHeader: "real_ip"},
+			"svc-b": {AppProtocol: "http", LBIPHeader: "real_ip"},
+		})
+		g.Expect(merged).To(Equal(map[string]string{LBIPHeader: "real_ip"}))
+		g.Expect(conflicts).To(HaveLen(1))
+		g.Expect(conflicts[0].Key).To(Equal(AppProtocol))
+		g.Expect(conflicts[0].Error()).To(Equal(`annotation servers.com/app-protocol has conflicting values on services: svc-a="http2", svc-b="http"`))
+	})
+}
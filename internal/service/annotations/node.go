@@ -0,0 +1,24 @@
+package annotations
+
+const (
+	UpstreamWeight = "servers.com/upstream-weight"
+	UpstreamDrain  = "servers.com/upstream-drain"
+
+	// DefaultUpstreamWeight is a weight of nodes without weight annotation
+	DefaultUpstreamWeight = 1
+	// MaxUpstreamWeight is a max weight of upstream
+	MaxUpstreamWeight = 100
+)
+
+// GetUpstreamWeight returns weight of node upstreams in every upstream zone, 0 for drained nodes.
+// Invalid values are skipped, they are reported by ValidateNodeAnnotations.
+func GetUpstreamWeight(annotations map[string]string) int {
+	if drain, _ := ParseBool(annotations[UpstreamDrain]); drain {
+		return 0
+	}
+	weight, err := ParseIntInRange(annotations[UpstreamWeight], 1, MaxUpstreamWeight)
+	if err != nil {
+		return DefaultUpstreamWeight
+	}
+	return weight
+}
@@ -0,0 +1,17 @@
+package annotations
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestGetUpstreamWeight(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetUpstreamWeight(map[string]string{})).To(Equal(DefaultUpstreamWeight))
+	g.Expect(GetUpstreamWeight(map[string]string{UpstreamWeight: "5"})).To(Equal(5))
+	g.Expect(GetUpstreamWeight(map[string]string{UpstreamWeight: "0"})).To(Equal(DefaultUpstreamWeight))
+	g.Expect(GetUpstreamWeight(map[string]string{UpstreamWeight: "5", UpstreamDrain: "true"})).To(Equal(0))
+	g.Expect(GetUpstreamWeight(map[string]string{UpstreamWeight: "5", UpstreamDrain: "false"})).To(Equal(5))
+}
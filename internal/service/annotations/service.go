@@ -1,7 +1,7 @@
 package annotations
 
 import (
-	"strconv"
+	"fmt"
 	"strings"
 
 	serverscom "github.com/serverscom/serverscom-go-client/pkg"
@@ -19,9 +19,51 @@ const (
 	AppHealthcheckJitter         = "servers.com/app-healthcheck-jitter"
 	LBIPHeader                   = "servers.com/load-balancer-ip-header"
 	LBIPSubnets                  = "servers.com/load-balancer-ip-subnets"
+	BackendProtocol              = "servers.com/backend-protocol"
+	SessionAffinity              = "servers.com/session-affinity"
+	ProxyConnectTimeout          = "servers.com/proxy-connect-timeout"
+	ProxyReadTimeout             = "servers.com/proxy-read-timeout"
+	ProxySendTimeout             = "servers.com/proxy-send-timeout"
+	UpstreamKeepaliveTimeout     = "servers.com/upstream-keepalive-timeout"
+	ProxyBodySize                = "servers.com/proxy-body-size"
+
+	BackendProtocolHTTP  = "HTTP"
+	BackendProtocolHTTPS = "HTTPS"
+	BackendProtocolGRPC  = "GRPC"
+	BackendProtocolGRPCS = "GRPCS"
+
+	// h2cProtocol is HTTP/2 without TLS, balancer can't use it for upstreams
+	h2cProtocol = "H2C"
+	// h2cAppProtocol is ServicePort.AppProtocol of HTTP/2 without TLS
+	h2cAppProtocol = "kubernetes.io/h2c"
+
+	SessionAffinityNone     = "None"
+	SessionAffinityClientIP = "ClientIP"
+
+	// IPHashMethod is a balancing method which sends requests of a client to the same upstream
+	IPHashMethod = "ip_hash"
 )
 
+// unsupportedAnnotations are validated service annotations which have no counterpart
+// in upstream and vhost zones of the L7 balancer API yet
+var unsupportedAnnotations = []string{
+	ProxyConnectTimeout,
+	ProxyReadTimeout,
+	ProxySendTimeout,
+	UpstreamKeepaliveTimeout,
+	ProxyBodySize,
+}
+
+// appProtocols maps ServicePort.AppProtocol values to backend protocols
+var appProtocols = map[string]string{
+	"http":  BackendProtocolHTTP,
+	"https": BackendProtocolHTTPS,
+	"grpc":  BackendProtocolGRPC,
+	"grpcs": BackendProtocolGRPCS,
+}
+
 // FillLBVHostZoneWithServiceAnnotations prepares the LB vhost zone input based on annotations.
+// Invalid annotation values are skipped, they are reported by ValidateServiceAnnotations.
 func FillLBVHostZoneWithServiceAnnotations(vZInput *serverscom.L7VHostZoneInput, annotations map[string]string) *serverscom.L7VHostZoneInput {
 	// AppProtocol annotation
 	if value, ok := annotations[AppProtocol]; ok {
@@ -32,11 +74,14 @@ func FillLBVHostZoneWithServiceAnnotations(vZInput *serverscom.L7VHostZoneInput,
 
 	// LBIPHeader & LBIPSubnets annotations
 	if value, ok := annotations[LBIPHeader]; ok {
-		vZInput.RealIPHeader = new(serverscom.RealIPHeader)
-		vZInput.RealIPHeader.Name = ParseRealIPHeaderName(value)
-		if subnets, ok := annotations[LBIPSubnets]; ok {
-			s := strings.Split(strings.ReplaceAll(subnets, " ", ""), ",")
-			vZInput.RealIPHeader.Networks = s
+		if name, err := ParseOneOfFold(value, RealIPHeaderNames); err == nil {
+			vZInput.RealIPHeader = new(serverscom.RealIPHeader)
+			vZInput.RealIPHeader.Name = serverscom.RealIPHeaderName(name)
+			if subnets, ok := annotations[LBIPSubnets]; ok {
+				if s, err := ParseCIDRList(subnets); err == nil {
+					vZInput.RealIPHeader.Networks = s
+				}
+			}
 		}
 	}
 
@@ -44,51 +89,58 @@ func FillLBVHostZoneWithServiceAnnotations(vZInput *serverscom.L7VHostZoneInput,
 }
 
 // FillLBUpstreamZoneWithServiceAnnotations prepares the LB upstream zone input based on annotations.
+// Invalid annotation values are skipped, they are reported by ValidateServiceAnnotations.
 func FillLBUpstreamZoneWithServiceAnnotations(uZInput *serverscom.L7UpstreamZoneInput, annotations map[string]string) *serverscom.L7UpstreamZoneInput {
 	// LBBalancingAlgorithm annotation
 	if value, ok := annotations[LBBalancingAlgorithm]; ok {
-		uZInput.Method = &value
+		if val, err := ParseBalancingAlgorithm(value); err == nil {
+			uZInput.Method = &val
+		}
 	}
 
 	// AppHealthcheckPath annotation
 	if value, ok := annotations[AppHealthcheckPath]; ok {
-		uZInput.HCPath = &value
+		if val, err := ParsePath(value); err == nil {
+			uZInput.HCPath = &val
+		}
 	}
 
 	// AppHealthcheckDomain annotation
-	if value, ok := annotations[AppHealthcheckDomain]; ok {
+	if value, ok := annotations[AppHealthcheckDomain]; ok && value != "" {
 		uZInput.HCDomain = &value
 	}
 
 	// AppHealthcheckRequestsMethod annotation
 	if value, ok := annotations[AppHealthcheckRequestsMethod]; ok {
-		uZInput.HCMethod = &value
+		if val, err := ParseOneOf(value, HealthcheckMethods); err == nil {
+			uZInput.HCMethod = &val
+		}
 	}
 
 	// AppHealthcheckCheckToFail annotation
 	if value, ok := annotations[AppHealthcheckCheckToFail]; ok {
-		if val, err := strconv.Atoi(value); err == nil {
+		if val, err := ParseIntInRange(value, 1, 100); err == nil {
 			uZInput.HCFails = &val
 		}
 	}
 
 	// AppHealthcheckChecksToPass annotation
 	if value, ok := annotations[AppHealthcheckChecksToPass]; ok {
-		if val, err := strconv.Atoi(value); err == nil {
+		if val, err := ParseIntInRange(value, 1, 100); err == nil {
 			uZInput.HCPasses = &val
 		}
 	}
 
 	// AppHealthcheckInterval annotation
 	if value, ok := annotations[AppHealthcheckInterval]; ok {
-		if val, err := strconv.Atoi(value); err == nil {
+		if val, err := ParseIntInRange(value, 1, 3600); err == nil {
 			uZInput.HCInterval = &val
 		}
 	}
 
 	// AppHealthcheckJitter annotation
 	if value, ok := annotations[AppHealthcheckJitter]; ok {
-		if val, err := strconv.Atoi(value); err == nil {
+		if val, err := ParseIntInRange(value, 0, 3600); err == nil {
 			uZInput.HCJitter = &val
 		}
 	}
@@ -96,14 +148,63 @@ func FillLBUpstreamZoneWithServiceAnnotations(uZInput *serverscom.L7UpstreamZone
 	return uZInput
 }
 
-// ParseRealIPHeaderName parses the Real IP Header Name from annotation
-func ParseRealIPHeaderName(input string) serverscom.RealIPHeaderName {
-	switch input {
-	case string(serverscom.RealIP):
-		return serverscom.RealIP
-	case string(serverscom.ForwardedFor):
-		return serverscom.ForwardedFor
-	default:
-		return ""
+// GetUnsupportedAnnotations returns service annotations which can't be applied to the balancer
+func GetUnsupportedAnnotations(annotations map[string]string) []string {
+	var res []string
+	for _, k := range unsupportedAnnotations {
+		if _, ok := annotations[k]; ok {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// GetBackendProtocol returns protocol balancer uses to connect to service port.
+// Service annotation takes precedence over port appProtocol, unknown values are ignored.
+// Returns BackendProtocolHTTP by default.
+func GetBackendProtocol(annotations map[string]string, appProtocol *string) string {
+	if value, ok := annotations[BackendProtocol]; ok {
+		if protocol, err := ParseOneOfFold(value, BackendProtocols); err == nil {
+			return protocol
+		}
+	}
+	if appProtocol != nil {
+		if protocol, ok := appProtocols[strings.ToLower(*appProtocol)]; ok {
+			return protocol
+		}
+	}
+	return BackendProtocolHTTP
+}
+
+// CheckAppProtocol returns an error if port appProtocol isn't supported and HTTP is used instead of it.
+// Backend protocol annotation takes precedence over appProtocol, so it isn't checked then.
+func CheckAppProtocol(annotations map[string]string, appProtocol *string) error {
+	if _, ok := annotations[BackendProtocol]; ok || appProtocol == nil {
+		return nil
+	}
+	if strings.EqualFold(*appProtocol, h2cAppProtocol) {
+		return fmt.Errorf("appProtocol %s isn't supported, the balancer speaks HTTP/2 to upstreams only as gRPC transport, HTTP is used", *appProtocol)
+	}
+	return nil
+}
+
+// IsGRPCBackendProtocol returns true if backend is a gRPC one, its clients must use HTTP/2
+func IsGRPCBackendProtocol(protocol string) bool {
+	return protocol == BackendProtocolGRPC || protocol == BackendProtocolGRPCS
+}
+
+// FillLBUpstreamZoneWithBackendProtocol configures upstream zone connections for backend protocol
+func FillLBUpstreamZoneWithBackendProtocol(uZInput *serverscom.L7UpstreamZoneInput, protocol string) *serverscom.L7UpstreamZoneInput {
+	switch protocol {
+	case BackendProtocolHTTPS:
+		uZInput.SSL = true
+	case BackendProtocolGRPC:
+		grpc := true
+		uZInput.GRPC = &grpc
+	case BackendProtocolGRPCS:
+		grpc := true
+		uZInput.GRPC = &grpc
+		uZInput.SSL = true
 	}
+	return uZInput
 }
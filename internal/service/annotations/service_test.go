@@ -28,6 +28,13 @@ func TestFillLBVHostZoneWithServiceAnnotations(t *testing.T) {
 	g.Expect(result.HTTP2).To(BeTrue())
 	g.Expect(result.RealIPHeader.Name).To(BeEquivalentTo(string(serverscom.RealIP)))
 	g.Expect(result.RealIPHeader.Networks).To(BeEquivalentTo([]string{"192.168.1.0/24", "10.0.0.0/8"}))
+
+	invalidAnnotations := map[string]string{
+		LBIPHeader:  "x-real-ip",
+		LBIPSubnets: "192.168.1.0/24",
+	}
+	result = FillLBVHostZoneWithServiceAnnotations(&serverscom.L7VHostZoneInput{}, invalidAnnotations)
+	g.Expect(result.RealIPHeader).To(BeNil())
 }
 
 func TestFillLBUpstreamZoneWithServiceAnnotations(t *testing.T) {
@@ -36,12 +43,18 @@ func TestFillLBUpstreamZoneWithServiceAnnotations(t *testing.T) {
 	uZInput := &serverscom.L7UpstreamZoneInput{}
 
 	invalidAnnotations := map[string]string{
-		AppHealthcheckCheckToFail:  "",
-		AppHealthcheckChecksToPass: "",
-		AppHealthcheckInterval:     "",
-		AppHealthcheckJitter:       "",
+		LBBalancingAlgorithm:         "random",
+		AppHealthcheckPath:           "health",
+		AppHealthcheckRequestsMethod: "DELETE",
+		AppHealthcheckCheckToFail:    "",
+		AppHealthcheckChecksToPass:   "0",
+		AppHealthcheckInterval:       "",
+		AppHealthcheckJitter:         "-1",
 	}
 	result := FillLBUpstreamZoneWithServiceAnnotations(uZInput, invalidAnnotations)
+	g.Expect(result.Method).To(BeNil())
+	g.Expect(result.HCPath).To(BeNil())
+	g.Expect(result.HCMethod).To(BeNil())
 	g.Expect(result.HCFails).To(BeNil())
 	g.Expect(result.HCPasses).To(BeNil())
 	g.Expect(result.HCInterval).To(BeNil())
@@ -69,3 +82,68 @@ func TestFillLBUpstreamZoneWithServiceAnnotations(t *testing.T) {
 	g.Expect(*result.HCInterval).To(Equal(10))
 	g.Expect(*result.HCJitter).To(Equal(5))
 }
+
+func TestGetBackendProtocol(t *testing.T) {
+	g := NewWithT(t)
+
+	appProtocol := func(v string) *string { return &v }
+
+	g.Expect(GetBackendProtocol(nil, nil)).To(Equal(BackendProtocolHTTP))
+	g.Expect(GetBackendProtocol(map[string]string{BackendProtocol: "grpc"}, nil)).To(Equal(BackendProtocolGRPC))
+	g.Expect(GetBackendProtocol(nil, appProtocol("HTTPS"))).To(Equal(BackendProtocolHTTPS))
+	// h2c upstreams aren't supported, they are proxied with HTTP/1.1
+	g.Expect(GetBackendProtocol(nil, appProtocol("kubernetes.io/h2c"))).To(Equal(BackendProtocolHTTP))
+	g.Expect(GetBackendProtocol(map[string]string{BackendProtocol: "h2c"}, nil)).To(Equal(BackendProtocolHTTP))
+	g.Expect(GetBackendProtocol(nil, appProtocol("kubernetes.io/ws"))).To(Equal(BackendProtocolHTTP))
+
+	// h2c appProtocol is reported instead of being replaced silently
+	g.Expect(CheckAppProtocol(nil, nil)).To(Succeed())
+	g.Expect(CheckAppProtocol(nil, appProtocol("grpc"))).To(Succeed())
+	g.Expect(CheckAppProtocol(nil, appProtocol("kubernetes.io/h2c"))).To(MatchError(
+		"appProtocol kubernetes.io/h2c isn't supported, the balancer speaks HTTP/2 to upstreams only as gRPC transport, HTTP is used"))
+	g.Expect(CheckAppProtocol(map[string]string{BackendProtocol: "GRPC"}, appProtocol("kubernetes.io/h2c"))).To(Succeed())
+
+	// annotation takes precedence over app protocol, invalid annotation is ignored
+	g.Expect(GetBackendProtocol(map[string]string{BackendProtocol: "GRPCS"}, appProtocol("http"))).To(Equal(BackendProtocolGRPCS))
+	g.Expect(GetBackendProtocol(map[string]string{BackendProtocol: "SPDY"}, appProtocol("https"))).To(Equal(BackendProtocolHTTPS))
+}
+
+func TestFillLBUpstreamZoneWithBackendProtocol(t *testing.T) {
+	tests := []struct {
+		protocol string
+		ssl      bool
+		grpc     bool
+	}{
+		{protocol: BackendProtocolHTTP},
+		{protocol: BackendProtocolHTTPS, ssl: true},
+		{protocol: BackendProtocolGRPC, grpc: true},
+		{protocol: BackendProtocolGRPCS, ssl: true, grpc: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.protocol, func(t *testing.T) {
+			g := NewWithT(t)
+
+			result := FillLBUpstreamZoneWithBackendProtocol(&serverscom.L7UpstreamZoneInput{}, tc.protocol)
+			g.Expect(result.SSL).To(Equal(tc.ssl))
+			if tc.grpc {
+				g.Expect(result.GRPC).NotTo(BeNil())
+				g.Expect(*result.GRPC).To(BeTrue())
+			} else {
+				g.Expect(result.GRPC).To(BeNil())
+			}
+			g.Expect(IsGRPCBackendProtocol(tc.protocol)).To(Equal(tc.grpc))
+		})
+	}
+}
+
+func TestGetUnsupportedAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(GetUnsupportedAnnotations(map[string]string{LBBalancingAlgorithm: "round_robin"})).To(BeEmpty())
+	g.Expect(GetUnsupportedAnnotations(map[string]string{
+		LBBalancingAlgorithm: "round_robin",
+		ProxyBodySize:        "10Mi",
+		ProxyReadTimeout:     "10m",
+	})).To(Equal([]string{ProxyReadTimeout, ProxyBodySize}))
+}
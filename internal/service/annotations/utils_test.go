@@ -21,3 +21,11 @@ func TestGetRegionIDByCode(t *testing.T) {
 		g.Expect(id).To(BeEquivalentTo(1))
 	})
 }
+
+func TestEditDistance(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(editDistance("", "")).To(Equal(0))
+	g.Expect(editDistance("abc", "")).To(Equal(3))
+	g.Expect(editDistance("geoip", "geo-ip")).To(Equal(1))
+	g.Expect(editDistance("kitten", "sitting")).To(Equal(3))
+}
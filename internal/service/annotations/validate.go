@@ -0,0 +1,367 @@
+package annotations
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+const (
+	// Prefix is a common prefix for all annotations handled by controller
+	Prefix = "servers.com/"
+
+	// LBCertificatePrefix is a prefix of annotations which override ingress tls certs for a host
+	LBCertificatePrefix = "servers.com/certificate-"
+
+	// maxSuggestionDistance is a max edit distance between unknown and known annotation
+	// to consider unknown annotation as misspelled
+	maxSuggestionDistance = 3
+)
+
+var (
+	annotationsPath = field.NewPath("metadata", "annotations")
+
+	TLSVersions         = []string{"TLSv1.0", "TLSv1.1", "TLSv1.2", "TLSv1.3"}
+	BalancingAlgorithms = []string{"random.least_conn", "round_robin", "least_conn", "ip_hash"}
+	// BalancingAlgorithmAliases are spellings accepted by earlier releases, they are passed as is
+	BalancingAlgorithmAliases = []string{"round-robin", "least-connections"}
+	HealthcheckMethods        = []string{"GET", "HEAD", "POST"}
+	AppProtocols              = []string{"http", "http2"}
+	RealIPHeaderNames         = []string{string(serverscom.RealIP), string(serverscom.ForwardedFor)}
+	ReclaimPolicies           = []string{ReclaimPolicyRetain, ReclaimPolicyDelete}
+	SessionAffinities         = []string{SessionAffinityNone, SessionAffinityClientIP}
+	BackendProtocols          = []string{BackendProtocolHTTP, BackendProtocolHTTPS, BackendProtocolGRPC, BackendProtocolGRPCS}
+)
+
+// validator validates a single annotation value
+type validator func(value string) error
+
+// prefixValidator validates annotation with variable suffix
+type prefixValidator func(suffix, value string) error
+
+// ingressValidators contains validators for all known ingress annotations
+var ingressValidators = map[string]validator{
+	LBStoreLogs:             func(v string) error { _, err := ParseBool(v); return err },
+	LBStoreLogsRegionCode:   validateStoreLogsRegionCode,
+	LBGeoIPEnabled:          func(v string) error { _, err := ParseBool(v); return err },
+	LBMinTLSVersion:         func(v string) error { _, err := ParseOneOf(v, TLSVersions); return err },
+	LBClusterID:             validateNotEmpty,
+	LBRealIPHeader:          func(v string) error { _, err := ParseOneOfFold(v, RealIPHeaderNames); return err },
+	LBRealIPTrustedNetworks: func(v string) error { _, err := ParseCIDRList(v); return err },
+	LBGroup:                 validateDNSLabel,
+	LBID:                    validateNotEmpty,
+	LBAdopt:                 func(v string) error { _, err := ParseBool(v); return err },
+	LBName:                  validateDNSLabel,
+	SSLRedirect:             func(v string) error { _, err := ParseBool(v); return err },
+	ListenPorts:             func(v string) error { _, err := ParsePortList(v); return err },
+	Canary:                  func(v string) error { _, err := ParseBool(v); return err },
+	CanaryWeight:            func(v string) error { _, err := ParseIntInRange(v, 0, 100); return err },
+	WhitelistSourceRange:    func(v string) error { _, err := ParseCIDRList(v); return err },
+	LBReclaimPolicy:         func(v string) error { _, err := ParseOneOf(v, ReclaimPolicies); return err },
+}
+
+// ingressPrefixValidators contains validators for ingress annotations with variable suffix
+var ingressPrefixValidators = map[string]prefixValidator{
+	LBCertificatePrefix: func(_, v string) error { return validateNotEmpty(v) },
+	VHostPrefix:         validateVHostAnnotation,
+}
+
+// serviceValidators contains validators for all known service annotations
+var serviceValidators = map[string]validator{
+	LBBalancingAlgorithm:         func(v string) error { _, err := ParseBalancingAlgorithm(v); return err },
+	AppProtocol:                  func(v string) error { _, err := ParseOneOfFold(v, AppProtocols); return err },
+	AppHealthcheckPath:           func(v string) error { _, err := ParsePath(v); return err },
+	AppHealthcheckDomain:         validateNotEmpty,
+	AppHealthcheckRequestsMethod: func(v string) error { _, err := ParseOneOf(v, HealthcheckMethods); return err },
+	AppHealthcheckCheckToFail:    func(v string) error { _, err := ParseIntInRange(v, 1, 100); return err },
+	AppHealthcheckChecksToPass:   func(v string) error { _, err := ParseIntInRange(v, 1, 100); return err },
+	AppHealthcheckInterval:       func(v string) error { _, err := ParseIntInRange(v, 1, 3600); return err },
+	AppHealthcheckJitter:         func(v string) error { _, err := ParseIntInRange(v, 0, 3600); return err },
+	LBIPHeader:                   func(v string) error { _, err := ParseOneOfFold(v, RealIPHeaderNames); return err },
+	LBIPSubnets:                  func(v string) error { _, err := ParseCIDRList(v); return err },
+	BackendProtocol:              validateBackendProtocol,
+	SessionAffinity:              func(v string) error { _, err := ParseOneOf(v, SessionAffinities); return err },
+	ProxyConnectTimeout:          func(v string) error { _, err := ParseDuration(v, time.Second, time.Hour); return err },
+	ProxyReadTimeout:             func(v string) error { _, err := ParseDuration(v, time.Second, 24*time.Hour); return err },
+	ProxySendTimeout:             func(v string) error { _, err := ParseDuration(v, time.Second, 24*time.Hour); return err },
+	UpstreamKeepaliveTimeout:     func(v string) error { _, err := ParseDuration(v, time.Second, time.Hour); return err },
+	ProxyBodySize:                func(v string) error { _, err := ParseSize(v); return err },
+}
+
+// nodeValidators contains validators for all known node annotations
+var nodeValidators = map[string]validator{
+	UpstreamWeight: func(v string) error { _, err := ParseIntInRange(v, 1, MaxUpstreamWeight); return err },
+	UpstreamDrain:  func(v string) error { _, err := ParseBool(v); return err },
+}
+
+// ValidateIngressAnnotations validates all servers.com annotations of an ingress.
+// Unknown annotations with servers.com prefix are reported as errors.
+// Returns an aggregated error with all found problems or nil.
+func ValidateIngressAnnotations(annotations map[string]string) error {
+	allErrs := validateAnnotations(annotations, ingressValidators, ingressPrefixValidators, true)
+
+	// logs can't be stored without a region
+	if value, ok := annotations[LBStoreLogs]; ok {
+		region, hasRegion := annotations[LBStoreLogsRegionCode]
+		if enabled, err := ParseBool(value); err == nil && enabled && (!hasRegion || region == StoreLogsOff) {
+			allErrs = append(allErrs, field.Invalid(annotationsPath.Key(LBStoreLogs), value,
+				fmt.Sprintf("requires a region code in %s annotation", LBStoreLogsRegionCode)))
+		}
+	}
+	return allErrs.ToAggregate()
+}
+
+// ValidateServiceAnnotations validates all servers.com annotations of a service.
+// Services could have annotations of other servers.com components, so unknown
+// annotations are reported only if they look like misspelled known ones.
+// Returns an aggregated error with all found problems or nil.
+func ValidateServiceAnnotations(annotations map[string]string) error {
+	return validateAnnotations(annotations, serviceValidators, nil, false).ToAggregate()
+}
+
+// ValidateNodeAnnotations validates all servers.com annotations of a node.
+// Nodes could have annotations of other servers.com components, so unknown
+// annotations are reported only if they look like misspelled known ones.
+// Returns an aggregated error with all found problems or nil.
+func ValidateNodeAnnotations(annotations map[string]string) error {
+	return validateAnnotations(annotations, nodeValidators, nil, false).ToAggregate()
+}
+
+// validateAnnotations validates annotations with validators and collects all errors
+func validateAnnotations(annotations map[string]string, validators map[string]validator, prefixValidators map[string]prefixValidator, strict bool) field.ErrorList {
+	var allErrs field.ErrorList
+
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		if strings.HasPrefix(k, Prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := annotations[k]
+		fldPath := annotationsPath.Key(k)
+
+		if validate, ok := validators[k]; ok {
+			if err := validate(v); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath, v, err.Error()))
+			}
+			continue
+		}
+
+		if validate, suffix, ok := findPrefixValidator(k, prefixValidators); ok {
+			if err := validate(suffix, v); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath, v, err.Error()))
+			}
+			continue
+		}
+
+		suggestion := suggestAnnotation(k, validators)
+		switch {
+		case suggestion != "":
+			allErrs = append(allErrs, field.Invalid(fldPath, v, fmt.Sprintf("unknown annotation, did you mean %q?", suggestion)))
+		case strict:
+			allErrs = append(allErrs, field.Invalid(fldPath, v, "unknown annotation"))
+		}
+	}
+
+	return allErrs
+}
+
+// findPrefixValidator finds validator for annotation with variable suffix, returns validator and suffix
+func findPrefixValidator(key string, prefixValidators map[string]prefixValidator) (prefixValidator, string, bool) {
+	for prefix, validate := range prefixValidators {
+		if suffix, ok := strings.CutPrefix(key, prefix); ok && suffix != "" {
+			return validate, suffix, true
+		}
+	}
+	return nil, "", false
+}
+
+// suggestAnnotation returns known annotation closest to key or empty string if there is no similar one
+func suggestAnnotation(key string, validators map[string]validator) string {
+	suggestion := ""
+	bestDistance := maxSuggestionDistance + 1
+	for known := range validators {
+		d := editDistance(key, known)
+		if d < bestDistance || (d == bestDistance && known < suggestion) {
+			suggestion = known
+			bestDistance = d
+		}
+	}
+	return suggestion
+}
+
+// validateDNSLabel checks that value is a DNS-1123 label, e.g. load balancer group or name
+func validateDNSLabel(value string) error {
+	if errs := validation.IsDNS1123Label(value); len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// validateStoreLogsRegionCode checks that value is a known region code or StoreLogsOff
+func validateStoreLogsRegionCode(value string) error {
+	if value == StoreLogsOff {
+		return nil
+	}
+	_, err := ParseRegionCode(value)
+	return err
+}
+
+// validateNotEmpty checks that value is not empty
+func validateNotEmpty(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+// ParseRegionCode parses storage region code and returns its ID
+func ParseRegionCode(value string) (int, error) {
+	id, ok := GetStorageRegionIDByCode(value)
+	if !ok {
+		return 0, fmt.Errorf("unknown region code")
+	}
+	return id, nil
+}
+
+// ParseBalancingAlgorithm checks that value is one of BalancingAlgorithms or their aliases
+func ParseBalancingAlgorithm(value string) (string, error) {
+	for _, a := range BalancingAlgorithmAliases {
+		if value == a {
+			return value, nil
+		}
+	}
+	return ParseOneOf(value, BalancingAlgorithms)
+}
+
+// ParseOneOf checks that value is one of allowed values
+func ParseOneOf(value string, allowed []string) (string, error) {
+	for _, a := range allowed {
+		if value == a {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+}
+
+// ParseOneOfFold checks that value is one of allowed values ignoring case
+// and returns matched allowed value
+func ParseOneOfFold(value string, allowed []string) (string, error) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+}
+
+// ParseBool parses boolean value
+func ParseBool(value string) (bool, error) {
+	val, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("must be a boolean")
+	}
+	return val, nil
+}
+
+// ParsePortList parses comma separated list of unique ports
+func ParsePortList(value string) ([]int32, error) {
+	var ports []int32
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		port, err := strconv.ParseInt(p, 10, 32)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid port %q, must be a number between 1 and 65535", p)
+		}
+		if slices.Contains(ports, int32(port)) {
+			return nil, fmt.Errorf("duplicate port %d", port)
+		}
+		ports = append(ports, int32(port))
+	}
+	return ports, nil
+}
+
+// ParseIntInRange parses integer and checks it's in [min, max] range
+func ParseIntInRange(value string, min, max int) (int, error) {
+	val, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("must be an integer")
+	}
+	if val < min || val > max {
+		return 0, fmt.Errorf("must be between %d and %d", min, max)
+	}
+	return val, nil
+}
+
+// ParseDuration parses duration with units, e.g. 30s or 10m, in range from min to max
+func ParseDuration(value string, min, max time.Duration) (time.Duration, error) {
+	val, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("must be a duration with units, e.g. 30s or 10m")
+	}
+	if val < min || val > max {
+		return 0, fmt.Errorf("must be between %s and %s", min, max)
+	}
+	return val, nil
+}
+
+// ParseSize parses size in bytes with units, e.g. 100Mi or 1G, 0 means unlimited
+func ParseSize(value string) (int64, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("must be a size with units, e.g. 512Ki or 100Mi")
+	}
+	val, ok := q.AsInt64()
+	if !ok || val < 0 {
+		return 0, fmt.Errorf("must be a non negative number of bytes")
+	}
+	return val, nil
+}
+
+// ParsePath checks that value is an absolute path
+func ParsePath(value string) (string, error) {
+	if !strings.HasPrefix(value, "/") {
+		return "", fmt.Errorf("must be an absolute path")
+	}
+	return value, nil
+}
+
+// ParseCIDRList parses comma separated list of CIDRs
+func ParseCIDRList(value string) ([]string, error) {
+	var res []string
+	for _, s := range strings.Split(strings.ReplaceAll(value, " ", ""), ",") {
+		if s == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(s); err != nil {
+			return nil, fmt.Errorf("%q is not a valid CIDR", s)
+		}
+		res = append(res, s)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("must contain at least one CIDR")
+	}
+	return res, nil
+}
+
+// validateBackendProtocol validates backend protocol. Balancer speaks HTTP/2 to upstreams
+// only as gRPC transport, so HTTP/2 without TLS is rejected explicitly.
+func validateBackendProtocol(value string) error {
+	if strings.EqualFold(value, h2cProtocol) {
+		return fmt.Errorf("%s isn't supported, the balancer speaks HTTP/2 to upstreams only as gRPC transport", h2cProtocol)
+	}
+	_, err := ParseOneOfFold(value, BackendProtocols)
+	return err
+}
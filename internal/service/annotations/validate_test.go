@@ -0,0 +1,202 @@
+package annotations
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateIngressAnnotations(t *testing.T) {
+	t.Run("Valid annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBStoreLogs:                         "true",
+			LBStoreLogsRegionCode:               "US01",
+			LBGeoIPEnabled:                      "true",
+			LBMinTLSVersion:                     "TLSv1.3",
+			LBClusterID:                         "123",
+			LBGroup:                             "web",
+			LBID:                                "lb-id",
+			LBAdopt:                             "true",
+			LBReclaimPolicy:                     "Retain",
+			LBName:                              "shop-frontend",
+			SSLRedirect:                         "true",
+			ListenPorts:                         "80, 8080",
+			Canary:                              "true",
+			CanaryWeight:                        "20",
+			WhitelistSourceRange:                "10.0.0.0/8, 192.168.0.0/16",
+			LBCertificatePrefix + "example.com": "secret",
+			"kubernetes.io/ingress.class":       "serverscom",
+		}
+		g.Expect(ValidateIngressAnnotations(annotations)).To(Succeed())
+	})
+
+	t.Run("All errors are aggregated", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBStoreLogsRegionCode: "notexist",
+			LBGeoIPEnabled:        "invalid",
+			LBMinTLSVersion:       "TLSv9",
+			"servers.com/load-balancer-geo-ip-enable": "true",
+			"servers.com/something-else":              "value",
+			LBCertificatePrefix + "example.com":       "",
+			LBGroup:                                   "Web_Group",
+			LBAdopt:                                   "yes",
+			LBReclaimPolicy:                           "retain",
+			LBName:                                    "Shop.Frontend",
+			ListenPorts:                               "80,70000",
+			CanaryWeight:                              "101",
+		}
+		err := ValidateIngressAnnotations(annotations)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/certificate-example.com]: Invalid value: "": must not be empty`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-geo-ip-enabled]: Invalid value: "invalid": must be a boolean`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-geo-ip-enable]: Invalid value: "true": unknown annotation, did you mean "servers.com/load-balancer-geo-ip-enabled"?`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-min-tls-version]: Invalid value: "TLSv9": must be one of TLSv1.0, TLSv1.1, TLSv1.2, TLSv1.3`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-store-logs-region-code]: Invalid value: "notexist": unknown region code`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/something-else]: Invalid value: "value": unknown annotation`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-group]: Invalid value: "Web_Group": a lowercase RFC 1123 label`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-adopt]: Invalid value: "yes": must be a boolean`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-reclaim-policy]: Invalid value: "retain": must be one of Retain, Delete`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/load-balancer-name]: Invalid value: "Shop.Frontend": a lowercase RFC 1123 label`))
+		g.Expect(err.Error()).To(ContainSubstring(`metadata.annotations[servers.com/listen-ports]: Invalid value: "80,70000": invalid port "70000", must be a number between 1 and 65535`))
+	})
+
+	t.Run("Store logs without region", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(ValidateIngressAnnotations(map[string]string{LBStoreLogs: "false"})).To(Succeed())
+		g.Expect(ValidateIngressAnnotations(map[string]string{LBStoreLogsRegionCode: StoreLogsOff})).To(Succeed())
+
+		err := ValidateIngressAnnotations(map[string]string{LBStoreLogs: "true", LBStoreLogsRegionCode: StoreLogsOff})
+		g.Expect(err).To(MatchError(`metadata.annotations[servers.com/load-balancer-store-logs]: Invalid value: "true": requires a region code in servers.com/load-balancer-store-logs-region-code annotation`))
+	})
+}
+
+func TestValidateServiceAnnotations(t *testing.T) {
+	t.Run("Valid annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBBalancingAlgorithm:                    "round_robin",
+			AppProtocol:                             "HTTP2",
+			AppHealthcheckPath:                      "/health",
+			AppHealthcheckDomain:                    "example.com",
+			AppHealthcheckRequestsMethod:            "GET",
+			AppHealthcheckCheckToFail:               "3",
+			AppHealthcheckChecksToPass:              "2",
+			AppHealthcheckInterval:                  "10",
+			AppHealthcheckJitter:                    "0",
+			LBIPHeader:                              "real_ip",
+			LBIPSubnets:                             "192.168.1.0/24, 10.0.0.0/8",
+			ProxyConnectTimeout:                     "5s",
+			ProxyReadTimeout:                        "10m",
+			ProxySendTimeout:                        "1h",
+			UpstreamKeepaliveTimeout:                "75s",
+			ProxyBodySize:                           "100Mi",
+			"servers.com/load-balancer-location-id": "1",
+		}
+		g.Expect(ValidateServiceAnnotations(annotations)).To(Succeed())
+	})
+
+	t.Run("Balancing algorithm aliases", func(t *testing.T) {
+		g := NewWithT(t)
+		for _, alias := range BalancingAlgorithmAliases {
+			g.Expect(ValidateServiceAnnotations(map[string]string{LBBalancingAlgorithm: alias})).To(Succeed())
+		}
+	})
+
+	t.Run("All errors are aggregated", func(t *testing.T) {
+		g := NewWithT(t)
+		annotations := map[string]string{
+			LBBalancingAlgorithm:        "random",
+			AppHealthcheckPath:          "health",
+			AppHealthcheckCheckToFail:   "",
+			AppHealthcheckInterval:      "0",
+			LBIPHeader:                  "x-real-ip",
+			LBIPSubnets:                 "192.168.1.0/24,10.0.0.0",
+			BackendProtocol:             "h2c",
+			"servers.com/app-protocols": "http2",
+		}
+		err := ValidateServiceAnnotations(annotations)
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/app-healthcheck-checks-to-fail]: Invalid value: "": must be an integer`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/app-healthcheck-interval]: Invalid value: "0": must be between 1 and 3600`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/app-healthcheck-path]: Invalid value: "health": must be an absolute path`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/app-protocols]: Invalid value: "http2": unknown annotation, did you mean "servers.com/app-protocol"?`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/load-balancer-balancing-algorithm]: Invalid value: "random"`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/load-balancer-ip-header]: Invalid value: "x-real-ip": must be one of real_ip, forwarded_for`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/load-balancer-ip-subnets]: Invalid value: "192.168.1.0/24,10.0.0.0": "10.0.0.0" is not a valid CIDR`))
+		g.Expect(err.Error()).To(ContainSubstring(`[servers.com/backend-protocol]: Invalid value: "h2c": H2C isn't supported`))
+	})
+}
+
+func TestValidateNodeAnnotations(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(ValidateNodeAnnotations(map[string]string{
+		UpstreamWeight:                 "10",
+		UpstreamDrain:                  "false",
+		"servers.com/some-other-label": "x",
+	})).To(Succeed())
+
+	err := ValidateNodeAnnotations(map[string]string{UpstreamWeight: "0", UpstreamDrain: "yes"})
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/upstream-weight]: Invalid value: "0": must be between 1 and 100`))
+	g.Expect(err.Error()).To(ContainSubstring(`[servers.com/upstream-drain]: Invalid value: "yes"`))
+}
+
+func TestParseDuration(t *testing.T) {
+	g := NewWithT(t)
+
+	d, err := ParseDuration("1m30s", time.Second, time.Hour)
+	g.Expect(err).To(BeNil())
+	g.Expect(d).To(Equal(90 * time.Second))
+
+	_, err = ParseDuration("30", time.Second, time.Hour)
+	g.Expect(err).To(MatchError("must be a duration with units, e.g. 30s or 10m"))
+
+	_, err = ParseDuration("500ms", time.Second, time.Hour)
+	g.Expect(err).To(MatchError("must be between 1s and 1h0m0s"))
+}
+
+func TestParseSize(t *testing.T) {
+	g := NewWithT(t)
+
+	size, err := ParseSize("100Mi")
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(Equal(int64(100 * 1024 * 1024)))
+
+	size, err = ParseSize("1G")
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(Equal(int64(1000 * 1000 * 1000)))
+
+	size, err = ParseSize("0")
+	g.Expect(err).To(BeNil())
+	g.Expect(size).To(Equal(int64(0)))
+
+	_, err = ParseSize("100MB")
+	g.Expect(err).To(MatchError("must be a size with units, e.g. 512Ki or 100Mi"))
+
+	_, err = ParseSize("-1Mi")
+	g.Expect(err).To(MatchError("must be a non negative number of bytes"))
+}
+
+func TestParsePortList(t *testing.T) {
+	g := NewWithT(t)
+
+	ports, err := ParsePortList("80, 443,8080")
+	g.Expect(err).To(BeNil())
+	g.Expect(ports).To(Equal([]int32{80, 443, 8080}))
+
+	_, err = ParsePortList("")
+	g.Expect(err).To(MatchError(`invalid port "", must be a number between 1 and 65535`))
+
+	_, err = ParsePortList("0")
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = ParsePortList("http")
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = ParsePortList("80,80")
+	g.Expect(err).To(MatchError("duplicate port 80"))
+}
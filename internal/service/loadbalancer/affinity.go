@@ -0,0 +1,49 @@
+package loadbalancer
+
+import (
+	"fmt"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ApplySessionAffinity sets upstream zone balancing method according to service session affinity.
+// Session affinity annotation takes precedence over balancing algorithm annotation,
+// which takes precedence over spec.sessionAffinity. Returns warnings about ignored settings.
+func ApplySessionAffinity(uZInput *serverscom.L7UpstreamZoneInput, svc *corev1.Service) []string {
+	var warnings []string
+
+	affinity, fromAnnotation := svc.Annotations[annotations.SessionAffinity]
+	if fromAnnotation {
+		if _, err := annotations.ParseOneOf(affinity, annotations.SessionAffinities); err != nil {
+			// invalid annotation is reported by ValidateServiceAnnotations
+			affinity, fromAnnotation = "", false
+		}
+	}
+	if !fromAnnotation {
+		affinity = string(svc.Spec.SessionAffinity)
+	}
+
+	if affinity != annotations.SessionAffinityClientIP {
+		return nil
+	}
+
+	if uZInput.Method != nil && *uZInput.Method != annotations.IPHashMethod {
+		if !fromAnnotation {
+			return []string{fmt.Sprintf("spec.sessionAffinity %s is ignored, balancing algorithm %s is set", affinity, *uZInput.Method)}
+		}
+		warnings = append(warnings, fmt.Sprintf("balancing algorithm %s is ignored, session affinity %s requires %s", *uZInput.Method, affinity, annotations.IPHashMethod))
+	}
+
+	if cfg := svc.Spec.SessionAffinityConfig; cfg != nil && cfg.ClientIP != nil && cfg.ClientIP.TimeoutSeconds != nil &&
+		*cfg.ClientIP.TimeoutSeconds != corev1.DefaultClientIPServiceAffinitySeconds {
+		warnings = append(warnings, fmt.Sprintf("session affinity timeout %ds isn't supported, it's ignored", *cfg.ClientIP.TimeoutSeconds))
+	}
+
+	method := annotations.IPHashMethod
+	uZInput.Method = &method
+
+	return warnings
+}
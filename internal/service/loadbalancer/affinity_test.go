@@ -0,0 +1,90 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplySessionAffinity(t *testing.T) {
+	timeout := int32(60)
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		spec        corev1.ServiceSpec
+		method      string
+		warnings    []string
+	}{
+		{
+			name: "No affinity",
+		},
+		{
+			name:   "Spec affinity",
+			spec:   corev1.ServiceSpec{SessionAffinity: corev1.ServiceAffinityClientIP},
+			method: annotations.IPHashMethod,
+		},
+		{
+			name:        "Annotation overrides spec",
+			annotations: map[string]string{annotations.SessionAffinity: "None"},
+			spec:        corev1.ServiceSpec{SessionAffinity: corev1.ServiceAffinityClientIP},
+		},
+		{
+			name:        "Balancing algorithm overrides spec",
+			annotations: map[string]string{annotations.LBBalancingAlgorithm: "round_robin"},
+			spec:        corev1.ServiceSpec{SessionAffinity: corev1.ServiceAffinityClientIP},
+			method:      "round_robin",
+			warnings:    []string{"spec.sessionAffinity ClientIP is ignored, balancing algorithm round_robin is set"},
+		},
+		{
+			name: "Annotation overrides balancing algorithm",
+			annotations: map[string]string{
+				annotations.SessionAffinity:      "ClientIP",
+				annotations.LBBalancingAlgorithm: "least_conn",
+			},
+			method:   annotations.IPHashMethod,
+			warnings: []string{"balancing algorithm least_conn is ignored, session affinity ClientIP requires ip_hash"},
+		},
+		{
+			name:        "Invalid annotation falls back to spec",
+			annotations: map[string]string{annotations.SessionAffinity: "Cookie"},
+			spec:        corev1.ServiceSpec{SessionAffinity: corev1.ServiceAffinityClientIP},
+			method:      annotations.IPHashMethod,
+		},
+		{
+			name: "Timeout isn't supported",
+			spec: corev1.ServiceSpec{
+				SessionAffinity: corev1.ServiceAffinityClientIP,
+				SessionAffinityConfig: &corev1.SessionAffinityConfig{
+					ClientIP: &corev1.ClientIPConfig{TimeoutSeconds: &timeout},
+				},
+			},
+			method:   annotations.IPHashMethod,
+			warnings: []string{"session affinity timeout 60s isn't supported, it's ignored"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			svc := &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec:       tc.spec,
+			}
+			uz := annotations.FillLBUpstreamZoneWithServiceAnnotations(&serverscom.L7UpstreamZoneInput{}, svc.Annotations)
+
+			warnings := ApplySessionAffinity(uz, svc)
+			g.Expect(warnings).To(Equal(tc.warnings))
+			if tc.method == "" {
+				g.Expect(uz.Method).To(BeNil())
+			} else {
+				g.Expect(uz.Method).NotTo(BeNil())
+				g.Expect(*uz.Method).To(Equal(tc.method))
+			}
+		})
+	}
+}
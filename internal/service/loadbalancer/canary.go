@@ -0,0 +1,76 @@
+package loadbalancer
+
+import (
+	"fmt"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+)
+
+// canaryPath is a path of canary ingress which gets weight percent of traffic of the same stable path
+type canaryPath struct {
+	store.PathInfo
+	weight int
+}
+
+// getCanaryPaths returns paths of canary ingresses from the namespace of ingress by their hosts and paths.
+// The oldest canary wins if several canaries serve the same host and path.
+func (m *Manager) getCanaryPaths(ingress *networkv1.Ingress) map[store.HostPath]canaryPath {
+	if annotations.IsCanary(ingress.Annotations) {
+		return nil
+	}
+
+	res := make(map[store.HostPath]canaryPath)
+	for _, canary := range m.store.GetHostPathIngresses(ingress) {
+		if canary.Namespace != ingress.Namespace || !annotations.IsCanary(canary.Annotations) {
+			continue
+		}
+		hostsInfo, err := m.store.GetIngressHostsInfo(canary)
+		if err != nil {
+			m.recorder.Eventf(ingress, corev1.EventTypeWarning, "Canary", "canary ingress %s is ignored: %v", canary.Name, err)
+			continue
+		}
+		weight := annotations.GetCanaryWeight(canary.Annotations)
+		for host, hInfo := range hostsInfo {
+			if host == store.DefaultBackendHost {
+				continue
+			}
+			for _, p := range hInfo.Paths {
+				key := store.HostPath{Host: host, Path: p.Path}
+				if _, ok := res[key]; ok || p.DefaultBackend {
+					continue
+				}
+				res[key] = canaryPath{PathInfo: p, weight: weight}
+			}
+		}
+	}
+	return res
+}
+
+// getCanaryUpstreamID returns id of upstream zone which serves both stable and canary paths.
+// It doesn't depend on canary weight, so changing the weight only updates upstream weights.
+func getCanaryUpstreamID(stable store.PathInfo, canary store.PathInfo) string {
+	return fmt.Sprintf("upstream-zone-%s-%d-canary-%s-%d", stable.Service.Name, stable.NodePort, canary.Service.Name, canary.NodePort)
+}
+
+// getCanaryUpstreams returns upstreams of stable and canary nodes weighted so that
+// canary nodes get weight percent of requests in total and stable nodes get the rest.
+func getCanaryUpstreams(stable store.PathInfo, canary canaryPath) []serverscom.L7UpstreamInput {
+	stableTotal := getTotalWeight(getUpstreams(stable, 1))
+	canaryTotal := getTotalWeight(getUpstreams(canary.PathInfo, 1))
+	stableFactor := (100 - canary.weight) * canaryTotal
+	canaryFactor := canary.weight * stableTotal
+
+	var ups []serverscom.L7UpstreamInput
+	if stableFactor > 0 || canaryTotal == 0 {
+		ups = append(ups, getUpstreams(stable, max(stableFactor, 1))...)
+	}
+	if canaryFactor > 0 || stableTotal == 0 {
+		ups = append(ups, getUpstreams(canary.PathInfo, max(canaryFactor, 1))...)
+	}
+	return limitWeights(reduceWeights(ups))
+}
@@ -0,0 +1,47 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+)
+
+func TestGetCanaryUpstreams(t *testing.T) {
+	stable := store.PathInfo{NodePort: 30000, NodeIps: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}}
+	canaryNodes := store.PathInfo{NodePort: 30001, NodeIps: []string{"10.0.0.1"}}
+
+	tests := []struct {
+		name         string
+		weight       int
+		stableWeight int32
+		canaryWeight int32
+		stableCount  int
+		canaryCount  int
+	}{
+		{name: "Weight 0 sends all requests to stable nodes", weight: 0, stableWeight: 1, stableCount: 3},
+		{name: "Weight 100 sends all requests to canary nodes", weight: 100, canaryWeight: 1, canaryCount: 1},
+		{name: "Weights are reduced", weight: 25, stableWeight: 1, canaryWeight: 1, stableCount: 3, canaryCount: 1},
+		{name: "Weights respect node counts", weight: 10, stableWeight: 3, canaryWeight: 1, stableCount: 3, canaryCount: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			ups := getCanaryUpstreams(stable, canaryPath{PathInfo: canaryNodes, weight: tc.weight})
+			var stableCount, canaryCount int
+			for _, u := range ups {
+				if u.Port == 30000 {
+					stableCount++
+					g.Expect(u.Weight).To(Equal(tc.stableWeight))
+				} else {
+					canaryCount++
+					g.Expect(u.Weight).To(Equal(tc.canaryWeight))
+				}
+			}
+			g.Expect(stableCount).To(Equal(tc.stableCount))
+			g.Expect(canaryCount).To(Equal(tc.canaryCount))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package loadbalancer
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+)
+
+// MergeConflict describes a vhost zone of group member whose settings or TLS differ from
+// the vhost zone of an older member serving the same host, settings of the older member are used
+type MergeConflict struct {
+	// Member and Owner are indexes of inputs of the member and the older member
+	Member int
+	Owner  int
+	Host   string
+}
+
+// MergeLoadBalancerInputs merges inputs of ingresses of the same group into one input.
+// Inputs must be ordered from the oldest ingress, the first input is primary:
+// its name and balancer settings are used. Vhost zones of the same host are merged,
+// settings and locations of the older ingress win, differing settings are returned as conflicts.
+// Upstream zones are deduplicated by id.
+func MergeLoadBalancerInputs(inputs []*serverscom.L7LoadBalancerCreateInput) (*serverscom.L7LoadBalancerCreateInput, []MergeConflict) {
+	switch len(inputs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return inputs[0], nil
+	}
+
+	merged := *inputs[0]
+	vhostZones := make(map[string]serverscom.L7VHostZoneInput)
+	owners := make(map[string]int)
+	upstreamZones := make(map[string]serverscom.L7UpstreamZoneInput)
+	var conflicts []MergeConflict
+
+	for i, input := range inputs {
+		for _, vz := range input.VHostZones {
+			existing, ok := vhostZones[vz.ID]
+			if !ok {
+				vz.LocationZones = append([]serverscom.L7LocationZoneInput(nil), vz.LocationZones...)
+				vhostZones[vz.ID] = vz
+				owners[vz.ID] = i
+				continue
+			}
+			if !sameVHostSettings(existing, vz) {
+				conflicts = append(conflicts, MergeConflict{Member: i, Owner: owners[vz.ID], Host: strings.Join(vz.Domains, ",")})
+			}
+			locations := make(map[string]struct{})
+			for _, lz := range existing.LocationZones {
+				locations[lz.Location] = struct{}{}
+			}
+			for _, lz := range vz.LocationZones {
+				if _, ok := locations[lz.Location]; !ok {
+					existing.LocationZones = append(existing.LocationZones, lz)
+				}
+			}
+			vhostZones[vz.ID] = existing
+		}
+		for _, uz := range input.UpstreamZones {
+			if _, ok := upstreamZones[uz.ID]; !ok {
+				upstreamZones[uz.ID] = uz
+			}
+		}
+	}
+
+	merged.VHostZones = nil
+	for _, id := range sortedKeys(vhostZones) {
+		merged.VHostZones = append(merged.VHostZones, vhostZones[id])
+	}
+	merged.UpstreamZones = nil
+	for _, id := range sortedKeys(upstreamZones) {
+		merged.UpstreamZones = append(merged.UpstreamZones, upstreamZones[id])
+	}
+
+	return &merged, conflicts
+}
+
+// sameVHostSettings returns true if vhost zones have the same settings and TLS, locations aren't compared
+func sameVHostSettings(a, b serverscom.L7VHostZoneInput) bool {
+	a.LocationZones, b.LocationZones = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// sortedKeys returns sorted keys of map
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,76 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+)
+
+func TestMergeLoadBalancerInputs(t *testing.T) {
+	g := NewWithT(t)
+
+	merged, conflicts := MergeLoadBalancerInputs(nil)
+	g.Expect(merged).To(BeNil())
+	g.Expect(conflicts).To(BeNil())
+
+	geoip := true
+	primary := &serverscom.L7LoadBalancerCreateInput{
+		Name:  "ingress-group-default-web",
+		Geoip: &geoip,
+		VHostZones: []serverscom.L7VHostZoneInput{
+			{
+				ID:            "vhost-zone-example.com",
+				Domains:       []string{"example.com"},
+				SSL:           true,
+				LocationZones: []serverscom.L7LocationZoneInput{{Location: "/", UpstreamID: "upstream-zone-a-30000"}},
+			},
+		},
+		UpstreamZones: []serverscom.L7UpstreamZoneInput{{ID: "upstream-zone-a-30000"}},
+	}
+	merged, _ = MergeLoadBalancerInputs([]*serverscom.L7LoadBalancerCreateInput{primary})
+	g.Expect(merged).To(BeIdenticalTo(primary))
+
+	member := &serverscom.L7LoadBalancerCreateInput{
+		Name: "ingress-group-default-web",
+		VHostZones: []serverscom.L7VHostZoneInput{
+			{
+				ID:      "vhost-zone-example.com",
+				Domains: []string{"example.com"},
+				LocationZones: []serverscom.L7LocationZoneInput{
+					{Location: "/", UpstreamID: "upstream-zone-b-30001"},
+					{Location: "/api", UpstreamID: "upstream-zone-b-30001"},
+				},
+			},
+			{
+				ID:            "vhost-zone-api.com",
+				Domains:       []string{"api.com"},
+				LocationZones: []serverscom.L7LocationZoneInput{{Location: "/", UpstreamID: "upstream-zone-a-30000"}},
+			},
+		},
+		UpstreamZones: []serverscom.L7UpstreamZoneInput{{ID: "upstream-zone-a-30000"}, {ID: "upstream-zone-b-30001"}},
+	}
+
+	merged, conflicts = MergeLoadBalancerInputs([]*serverscom.L7LoadBalancerCreateInput{primary, member})
+	g.Expect(merged.Name).To(Equal("ingress-group-default-web"))
+	g.Expect(merged.Geoip).To(Equal(&geoip))
+	g.Expect(merged.VHostZones).To(HaveLen(2))
+	g.Expect(merged.VHostZones[0].ID).To(Equal("vhost-zone-api.com"))
+	g.Expect(merged.VHostZones[1].ID).To(Equal("vhost-zone-example.com"))
+	g.Expect(merged.VHostZones[1].SSL).To(BeTrue())
+	g.Expect(merged.VHostZones[1].LocationZones).To(Equal([]serverscom.L7LocationZoneInput{
+		{Location: "/", UpstreamID: "upstream-zone-a-30000"},
+		{Location: "/api", UpstreamID: "upstream-zone-b-30001"},
+	}))
+	g.Expect(merged.UpstreamZones).To(Equal([]serverscom.L7UpstreamZoneInput{
+		{ID: "upstream-zone-a-30000"},
+		{ID: "upstream-zone-b-30001"},
+	}))
+
+	// TLS of the newer member differs, settings of the primary are used
+	g.Expect(conflicts).To(Equal([]MergeConflict{{Member: 1, Owner: 0, Host: "example.com"}}))
+
+	// inputs aren't changed
+	g.Expect(primary.VHostZones[0].LocationZones).To(HaveLen(1))
+	g.Expect(primary.VHostZones).To(HaveLen(1))
+}
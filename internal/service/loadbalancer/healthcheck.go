@@ -0,0 +1,129 @@
+package loadbalancer
+
+import (
+	"strings"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// defaultProbePeriod and defaultProbeFailureThreshold are kubernetes defaults of unset probe fields
+	defaultProbePeriod           = 10
+	defaultProbeFailureThreshold = 3
+)
+
+// ApplyProbeHealthCheck sets health check path, domain, interval and checks to fail of upstream zone
+// from the HTTP readiness probe of the first pod which probes the target port of path service.
+// Only HTTP and HTTPS backends are checked, probe scheme must match backend protocol.
+// Returns false if no suitable probe is found.
+func ApplyProbeHealthCheck(uZInput *serverscom.L7UpstreamZoneInput, p store.PathInfo, protocol string, pods []*corev1.Pod) bool {
+	scheme := corev1.URISchemeHTTP
+	switch protocol {
+	case annotations.BackendProtocolHTTP:
+	case annotations.BackendProtocolHTTPS:
+		scheme = corev1.URISchemeHTTPS
+	default:
+		return false
+	}
+
+	targetPort, ok := getTargetPort(p.Service, p.NodePort)
+	if !ok {
+		return false
+	}
+
+	for _, pod := range pods {
+		port, ok := resolveContainerPort(targetPort, pod.Spec.Containers)
+		if !ok {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			probe := c.ReadinessProbe
+			if probe == nil || probe.HTTPGet == nil {
+				continue
+			}
+			probePort, ok := resolveContainerPort(probe.HTTPGet.Port, []corev1.Container{c})
+			if !ok || probePort != port {
+				continue
+			}
+			probeScheme := probe.HTTPGet.Scheme
+			if probeScheme == "" {
+				probeScheme = corev1.URISchemeHTTP
+			}
+			if probeScheme != scheme {
+				continue
+			}
+			applyProbe(uZInput, probe)
+			return true
+		}
+	}
+	return false
+}
+
+// applyProbe sets health check of upstream zone from HTTP probe
+func applyProbe(uZInput *serverscom.L7UpstreamZoneInput, probe *corev1.Probe) {
+	path := "/"
+	if val, err := annotations.ParsePath(probe.HTTPGet.Path); err == nil {
+		path = val
+	}
+	uZInput.HCPath = &path
+
+	for _, h := range probe.HTTPGet.HTTPHeaders {
+		if strings.EqualFold(h.Name, "Host") && h.Value != "" {
+			domain := h.Value
+			uZInput.HCDomain = &domain
+			break
+		}
+	}
+
+	interval := int(probe.PeriodSeconds)
+	if interval <= 0 {
+		interval = defaultProbePeriod
+	}
+	interval = min(interval, 3600)
+	uZInput.HCInterval = &interval
+
+	fails := int(probe.FailureThreshold)
+	if fails <= 0 {
+		fails = defaultProbeFailureThreshold
+	}
+	fails = min(fails, 100)
+	uZInput.HCFails = &fails
+}
+
+// getTargetPort returns target port of service port with nodePort
+func getTargetPort(svc *corev1.Service, nodePort int) (intstr.IntOrString, bool) {
+	if svc == nil {
+		return intstr.IntOrString{}, false
+	}
+	for _, port := range svc.Spec.Ports {
+		if int(port.NodePort) != nodePort {
+			continue
+		}
+		if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal == 0 {
+			// unset target port is the same as port
+			return intstr.FromInt32(port.Port), true
+		}
+		return port.TargetPort, true
+	}
+	return intstr.IntOrString{}, false
+}
+
+// resolveContainerPort returns number of port, named ports are looked up in containers
+func resolveContainerPort(port intstr.IntOrString, containers []corev1.Container) (int32, bool) {
+	if port.Type == intstr.Int {
+		return port.IntVal, port.IntVal > 0
+	}
+	for _, c := range containers {
+		for _, cp := range c.Ports {
+			if cp.Name == port.StrVal {
+				return cp.ContainerPort, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,112 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestApplyProbeHealthCheck(t *testing.T) {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http"), NodePort: 30080},
+				{Port: 9090, NodePort: 30090},
+			},
+		},
+	}
+	newPod := func(probe *corev1.Probe) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-1"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{Name: "sidecar", Ports: []corev1.ContainerPort{{Name: "metrics", ContainerPort: 9090}}},
+					{
+						Name:           "app",
+						Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+						ReadinessProbe: probe,
+					},
+				},
+			},
+		}
+	}
+	httpProbe := func(port intstr.IntOrString, scheme corev1.URIScheme) *corev1.Probe {
+		return &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path:        "/ready",
+					Port:        port,
+					Scheme:      scheme,
+					HTTPHeaders: []corev1.HTTPHeader{{Name: "host", Value: "app.example.com"}},
+				},
+			},
+			PeriodSeconds:    5,
+			FailureThreshold: 200,
+		}
+	}
+
+	t.Run("Probe of named target port", func(t *testing.T) {
+		g := NewWithT(t)
+		uZInput := serverscom.L7UpstreamZoneInput{}
+		pods := []*corev1.Pod{newPod(httpProbe(intstr.FromInt32(8080), ""))}
+		ok := ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30080}, annotations.BackendProtocolHTTP, pods)
+		g.Expect(ok).To(BeTrue())
+		g.Expect(*uZInput.HCPath).To(Equal("/ready"))
+		g.Expect(*uZInput.HCDomain).To(Equal("app.example.com"))
+		g.Expect(*uZInput.HCInterval).To(Equal(5))
+		g.Expect(*uZInput.HCFails).To(Equal(100))
+	})
+
+	t.Run("Defaults of unset probe fields", func(t *testing.T) {
+		g := NewWithT(t)
+		uZInput := serverscom.L7UpstreamZoneInput{}
+		probe := &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Port: intstr.FromString("http")}}}
+		ok := ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30080}, annotations.BackendProtocolHTTP, []*corev1.Pod{newPod(probe)})
+		g.Expect(ok).To(BeTrue())
+		g.Expect(*uZInput.HCPath).To(Equal("/"))
+		g.Expect(uZInput.HCDomain).To(BeNil())
+		g.Expect(*uZInput.HCInterval).To(Equal(defaultProbePeriod))
+		g.Expect(*uZInput.HCFails).To(Equal(defaultProbeFailureThreshold))
+	})
+
+	t.Run("Probe of other port", func(t *testing.T) {
+		g := NewWithT(t)
+		uZInput := serverscom.L7UpstreamZoneInput{}
+		pods := []*corev1.Pod{newPod(httpProbe(intstr.FromInt32(8080), ""))}
+		ok := ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30090}, annotations.BackendProtocolHTTP, pods)
+		g.Expect(ok).To(BeFalse())
+		g.Expect(uZInput.HCPath).To(BeNil())
+	})
+
+	t.Run("Probe scheme differs from backend protocol", func(t *testing.T) {
+		g := NewWithT(t)
+		uZInput := serverscom.L7UpstreamZoneInput{}
+		pods := []*corev1.Pod{newPod(httpProbe(intstr.FromInt32(8080), corev1.URISchemeHTTPS))}
+		g.Expect(ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30080}, annotations.BackendProtocolHTTP, pods)).To(BeFalse())
+		g.Expect(ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30080}, annotations.BackendProtocolHTTPS, pods)).To(BeTrue())
+	})
+
+	t.Run("GRPC backend", func(t *testing.T) {
+		g := NewWithT(t)
+		uZInput := serverscom.L7UpstreamZoneInput{}
+		pods := []*corev1.Pod{newPod(httpProbe(intstr.FromInt32(8080), ""))}
+		g.Expect(ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30080}, annotations.BackendProtocolGRPC, pods)).To(BeFalse())
+	})
+
+	t.Run("Annotations take precedence", func(t *testing.T) {
+		g := NewWithT(t)
+		uZInput := serverscom.L7UpstreamZoneInput{}
+		pods := []*corev1.Pod{newPod(httpProbe(intstr.FromInt32(8080), ""))}
+		ApplyProbeHealthCheck(&uZInput, store.PathInfo{Service: svc, NodePort: 30080}, annotations.BackendProtocolHTTP, pods)
+		annotations.FillLBUpstreamZoneWithServiceAnnotations(&uZInput, map[string]string{annotations.AppHealthcheckPath: "/healthz"})
+		g.Expect(*uZInput.HCPath).To(Equal("/healthz"))
+		g.Expect(*uZInput.HCInterval).To(Equal(5))
+	})
+}
@@ -3,6 +3,7 @@ package loadbalancer
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -20,6 +21,13 @@ type LoadBalancer struct {
 	previousInput *serverscom.L7LoadBalancerUpdateInput
 
 	deleted bool
+	// released load balancers are detached from controller and kept in portal
+	released bool
+	// adopted load balancers existed before controller took them over,
+	// they are released by default
+	adopted bool
+	// formerName is a name load balancer had before naming was changed
+	formerName string
 
 	lastRefresh time.Time
 
@@ -66,14 +74,126 @@ func (lb *LoadBalancer) Find(name string) bool {
 	return lb.id != ""
 }
 
+// FindByLabel finds load balancer labeled with key and value in portal,
+// sets lb id and remembers its name as former name if found
+func (lb *LoadBalancer) FindByLabel(key, value string) bool {
+	list, err := lb.lBService.
+		Collection().
+		SetParam("label_selector", key+"="+value).
+		SetParam("type", "l7").
+		Collect(context.Background())
+
+	if err != nil {
+		return false
+	}
+
+	for _, candidate := range list {
+		if candidate.Labels[key] == value {
+			lb.id = candidate.ID
+			lb.formerName = candidate.Name
+			break
+		}
+	}
+
+	return lb.id != ""
+}
+
+// Adopt takes over an existing load balancer with id.
+// Balancer must be labeled as owned by lb, be released by controller or adoption
+// must be confirmed explicitly, balancer owned by another resource is never adopted.
+func (lb *LoadBalancer) Adopt(id string, confirmed bool) error {
+	l7, err := lb.lBService.GetL7LoadBalancer(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("can't get load balancer %s: %v", id, err)
+	}
+
+	if _, err := lb.checkOwner(l7, confirmed); err != nil {
+		return err
+	}
+
+	lb.id = l7.ID
+	lb.state = l7
+	lb.markAdopted()
+	lb.lastRefresh = time.Now()
+
+	return nil
+}
+
+// Claim takes over load balancer found by name with the same ownership check as Adopt does.
+// Unlabeled balancers with default names were created by controller before labels were used, they are owned.
+func (lb *LoadBalancer) Claim(confirmed bool) error {
+	l7, err := lb.lBService.GetL7LoadBalancer(context.Background(), lb.id)
+	if err != nil {
+		return fmt.Errorf("can't get load balancer %s: %v", lb.id, err)
+	}
+
+	owned := l7.Labels[OwnerLabel] == "" && l7.Labels[ReleasedLabel] == "" && IsDefaultName(l7.Name)
+	if !owned {
+		if owned, err = lb.checkOwner(l7, confirmed); err != nil {
+			lb.id = ""
+			return err
+		}
+	}
+
+	lb.state = l7
+	if !owned || l7.Labels[AdoptedLabel] == "true" {
+		lb.markAdopted()
+	}
+	lb.lastRefresh = time.Now()
+
+	return nil
+}
+
+// markAdopted marks load balancer as adopted and labels it, so it's known as adopted after restarts
+func (lb *LoadBalancer) markAdopted() {
+	lb.adopted = true
+	input := *lb.createInput
+	input.Labels = adoptedLabels(input.Labels)
+	lb.createInput = &input
+}
+
+// adoptedLabels returns a copy of labels with AdoptedLabel
+func adoptedLabels(labels map[string]string) map[string]string {
+	res := map[string]string{AdoptedLabel: "true"}
+	for k, v := range labels {
+		res[k] = v
+	}
+	return res
+}
+
+// checkOwner checks that load balancer could be taken over by lb: it must be labeled as owned by lb,
+// be released by controller or taking over must be confirmed explicitly, balancer owned by another
+// resource is never taken over. Returns true if balancer is owned by lb.
+func (lb *LoadBalancer) checkOwner(l7 *serverscom.L7LoadBalancer, confirmed bool) (bool, error) {
+	// balancer of another ingress is never taken over, even if it has the same name
+	if uid, ownUID := l7.Labels[IngressUIDLabel], lb.createInput.Labels[IngressUIDLabel]; uid != "" && ownUID != "" && uid != ownUID {
+		return false, fmt.Errorf("load balancer %s belongs to ingress with uid %s", l7.ID, uid)
+	}
+
+	switch owner := l7.Labels[OwnerLabel]; {
+	case owner == lb.createInput.Name, owner != "" && owner == lb.formerName:
+		return true, nil
+	case owner != "":
+		return false, fmt.Errorf("load balancer %s is owned by %s", l7.ID, owner)
+	case l7.Labels[ReleasedLabel] != "":
+	case !confirmed:
+		return false, fmt.Errorf("load balancer %s has no %s label, adoption must be confirmed", l7.ID, OwnerLabel)
+	}
+	return false, nil
+}
+
 // Copy makes a copy of load balancer
 func (lb *LoadBalancer) Copy() *LoadBalancer {
 	return &LoadBalancer{
 		id:            lb.id,
 		state:         lb.state,
+		createInput:   lb.createInput,
 		currentInput:  lb.currentInput,
 		previousInput: lb.previousInput,
 		deleted:       lb.deleted,
+		released:      lb.released,
+		adopted:       lb.adopted,
+		formerName:    lb.formerName,
 		lBService:     lb.lBService,
 	}
 }
@@ -98,6 +218,9 @@ func (lb *LoadBalancer) IsChanged(newInput *serverscom.L7LoadBalancerUpdateInput
 // Sync create/update/delete load balancer depending on it state
 func (lb *LoadBalancer) Sync() (*serverscom.L7LoadBalancer, error) {
 	if lb.deleted {
+		if lb.released {
+			return nil, lb.release()
+		}
 		return nil, lb.delete()
 	}
 
@@ -113,6 +236,28 @@ func (lb *LoadBalancer) MarkAsDeleted() {
 	lb.deleted = true
 }
 
+// MarkAsReleased marks load balancer as deleted from manager but kept in portal
+func (lb *LoadBalancer) MarkAsReleased() {
+	lb.deleted = true
+	lb.released = true
+}
+
+// ReclaimPolicy returns reclaim policy from load balancer labels or empty string if it isn't set
+func (lb *LoadBalancer) ReclaimPolicy() string {
+	return lb.labels()[ReclaimPolicyLabel]
+}
+
+// labels returns labels of the latest load balancer input
+func (lb *LoadBalancer) labels() map[string]string {
+	switch {
+	case lb.currentInput != nil:
+		return lb.currentInput.Labels
+	case lb.createInput != nil:
+		return lb.createInput.Labels
+	}
+	return nil
+}
+
 // UpdateInput saves current input to previous and updates current input with newInput
 func (lb *LoadBalancer) UpdateInput(newInput *serverscom.L7LoadBalancerUpdateInput) {
 	lb.previousInput = lb.currentInput
@@ -128,6 +273,30 @@ func (lb *LoadBalancer) delete() error {
 	return nil
 }
 
+// release clears ownership label of load balancer, labels it as released and leaves it in portal
+func (lb *LoadBalancer) release() error {
+	labels := make(map[string]string)
+	if lb.state != nil {
+		for k, v := range lb.state.Labels {
+			labels[k] = v
+		}
+	}
+	if owner := lb.labels()[OwnerLabel]; owner != "" {
+		labels[ReleasedLabel] = owner
+	}
+	labels[OwnerLabel] = ""
+	// released balancer could be adopted by any ingress
+	if _, ok := labels[IngressUIDLabel]; ok {
+		labels[IngressUIDLabel] = ""
+	}
+	input := serverscom.L7LoadBalancerUpdateInput{Labels: labels}
+	if _, err := lb.lBService.UpdateL7LoadBalancer(context.Background(), lb.id, input); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // create creates load balancer in portal
 func (lb *LoadBalancer) create() (*serverscom.L7LoadBalancer, error) {
 	l7, err := lb.lBService.CreateL7LoadBalancer(context.Background(), *lb.createInput)
@@ -143,17 +312,30 @@ func (lb *LoadBalancer) create() (*serverscom.L7LoadBalancer, error) {
 	return l7, nil
 }
 
+// NewUpdateInput returns update input which applies all settings of create input
+// to an existing load balancer. Load balancer without cluster is shared between clusters.
+func NewUpdateInput(input *serverscom.L7LoadBalancerCreateInput) *serverscom.L7LoadBalancerUpdateInput {
+	updateInput := &serverscom.L7LoadBalancerUpdateInput{
+		Name:              input.Name,
+		StoreLogs:         input.StoreLogs,
+		StoreLogsRegionID: input.StoreLogsRegionID,
+		Geoip:             input.Geoip,
+		VHostZones:        input.VHostZones,
+		UpstreamZones:     input.UpstreamZones,
+		ClusterID:         input.ClusterID,
+		Labels:            input.Labels,
+	}
+	if updateInput.ClusterID == nil {
+		sharedCluster := true
+		updateInput.SharedCluster = &sharedCluster
+	}
+	return updateInput
+}
+
 // update updates load balancer in portal
 func (lb *LoadBalancer) update() (*serverscom.L7LoadBalancer, error) {
 	if lb.currentInput == nil {
-		lb.currentInput = &serverscom.L7LoadBalancerUpdateInput{
-			Name:              lb.createInput.Name,
-			StoreLogs:         lb.createInput.StoreLogs,
-			StoreLogsRegionID: lb.createInput.StoreLogsRegionID,
-			Geoip:             lb.createInput.Geoip,
-			VHostZones:        lb.createInput.VHostZones,
-			UpstreamZones:     lb.createInput.UpstreamZones,
-		}
+		lb.currentInput = NewUpdateInput(lb.createInput)
 	}
 	l7, err := lb.lBService.UpdateL7LoadBalancer(context.Background(), lb.id, *lb.currentInput)
 
@@ -0,0 +1,48 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+)
+
+func TestNewUpdateInput(t *testing.T) {
+	storeLogs := false
+	regionID := 1
+	geoip := true
+
+	t.Run("All settings are applied", func(t *testing.T) {
+		g := NewWithT(t)
+		clusterID := "cluster"
+		input := &serverscom.L7LoadBalancerCreateInput{
+			Name:              "lb",
+			LocationID:        1,
+			StoreLogs:         &storeLogs,
+			StoreLogsRegionID: &regionID,
+			Geoip:             &geoip,
+			VHostZones:        []serverscom.L7VHostZoneInput{{ID: "vhost"}},
+			UpstreamZones:     []serverscom.L7UpstreamZoneInput{{ID: "upstream"}},
+			ClusterID:         &clusterID,
+			Labels:            map[string]string{OwnerLabel: "lb"},
+		}
+		g.Expect(NewUpdateInput(input)).To(Equal(&serverscom.L7LoadBalancerUpdateInput{
+			Name:              "lb",
+			StoreLogs:         &storeLogs,
+			StoreLogsRegionID: &regionID,
+			Geoip:             &geoip,
+			VHostZones:        []serverscom.L7VHostZoneInput{{ID: "vhost"}},
+			UpstreamZones:     []serverscom.L7UpstreamZoneInput{{ID: "upstream"}},
+			ClusterID:         &clusterID,
+			Labels:            map[string]string{OwnerLabel: "lb"},
+		}))
+	})
+
+	t.Run("Load balancer without cluster is shared", func(t *testing.T) {
+		g := NewWithT(t)
+		updateInput := NewUpdateInput(&serverscom.L7LoadBalancerCreateInput{Name: "lb"})
+		g.Expect(updateInput.ClusterID).To(BeNil())
+		g.Expect(updateInput.SharedCluster).NotTo(BeNil())
+		g.Expect(*updateInput.SharedCluster).To(BeTrue())
+	})
+}
@@ -0,0 +1,50 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"strings"
+
+	networkv1 "k8s.io/api/networking/v1"
+)
+
+// exactLocationModifier is a location modifier of exact match
+const exactLocationModifier = "= "
+
+// invalidLocationChars contains characters which can't be used in a location
+const invalidLocationChars = " \t\r\n{};\"'\\"
+
+// GetPathLocations maps ingress path and its type to locations of a vhost zone:
+//   - Exact matches the path only, e.g. /foo matches /foo but not /foo/
+//   - Prefix matches the path elements, e.g. /foo matches /foo and /foo/bar but not /foobar
+//   - ImplementationSpecific and empty type use the path as is, which is a string prefix match
+//
+// Returns an error if the path can't be expressed as a location.
+func GetPathLocations(path string, pathType *networkv1.PathType) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %q must be absolute", path)
+	}
+	if strings.ContainsAny(path, invalidLocationChars) {
+		return nil, fmt.Errorf("path %q contains characters which can't be used in a location", path)
+	}
+
+	pt := networkv1.PathTypeImplementationSpecific
+	if pathType != nil && *pathType != "" {
+		pt = *pathType
+	}
+
+	switch pt {
+	case networkv1.PathTypeExact:
+		return []string{exactLocationModifier + path}, nil
+	case networkv1.PathTypePrefix:
+		// trailing slash is ignored, /foo/ is the same as /foo
+		trimmed := strings.TrimRight(path, "/")
+		if trimmed == "" {
+			return []string{"/"}, nil
+		}
+		return []string{exactLocationModifier + trimmed, trimmed + "/"}, nil
+	case networkv1.PathTypeImplementationSpecific:
+		return []string{path}, nil
+	default:
+		return nil, fmt.Errorf("path %q has unsupported type %q", path, pt)
+	}
+}
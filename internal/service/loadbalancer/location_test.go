@@ -0,0 +1,51 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	networkv1 "k8s.io/api/networking/v1"
+)
+
+func TestGetPathLocations(t *testing.T) {
+	exact := networkv1.PathTypeExact
+	prefix := networkv1.PathTypePrefix
+	implSpecific := networkv1.PathTypeImplementationSpecific
+	unknown := networkv1.PathType("Regex")
+
+	tests := []struct {
+		name      string
+		path      string
+		pathType  *networkv1.PathType
+		locations []string
+		err       string
+	}{
+		{name: "exact", path: "/foo", pathType: &exact, locations: []string{"= /foo"}},
+		{name: "exact with trailing slash", path: "/foo/", pathType: &exact, locations: []string{"= /foo/"}},
+		{name: "exact root", path: "/", pathType: &exact, locations: []string{"= /"}},
+		{name: "prefix", path: "/foo", pathType: &prefix, locations: []string{"= /foo", "/foo/"}},
+		{name: "prefix with trailing slash", path: "/foo/", pathType: &prefix, locations: []string{"= /foo", "/foo/"}},
+		{name: "prefix with several elements", path: "/foo/bar", pathType: &prefix, locations: []string{"= /foo/bar", "/foo/bar/"}},
+		{name: "prefix root", path: "/", pathType: &prefix, locations: []string{"/"}},
+		{name: "implementation specific", path: "/foo", pathType: &implSpecific, locations: []string{"/foo"}},
+		{name: "empty type", path: "/foo", locations: []string{"/foo"}},
+		{name: "relative path", path: "foo", pathType: &prefix, err: `path "foo" must be absolute`},
+		{name: "empty path", path: "", pathType: &exact, err: `path "" must be absolute`},
+		{name: "path with space", path: "/foo bar", pathType: &prefix, err: `path "/foo bar" contains characters which can't be used in a location`},
+		{name: "path with braces", path: "/foo{1}", pathType: &implSpecific, err: `path "/foo{1}" contains characters which can't be used in a location`},
+		{name: "unknown type", path: "/foo", pathType: &unknown, err: `path "/foo" has unsupported type "Regex"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			locations, err := GetPathLocations(tc.path, tc.pathType)
+			if tc.err != "" {
+				g.Expect(err).To(MatchError(tc.err))
+				return
+			}
+			g.Expect(err).To(BeNil())
+			g.Expect(locations).To(Equal(tc.locations))
+		})
+	}
+}
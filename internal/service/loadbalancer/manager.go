@@ -3,6 +3,7 @@ package loadbalancer
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -11,7 +12,10 @@ import (
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 
 	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
@@ -26,23 +30,49 @@ type LBManagerInterface interface {
 	GetIds() []string
 	TranslateIngressToLB(ingress *networkv1.Ingress, sslCerts map[string]string) (*serverscom.L7LoadBalancerCreateInput, error)
 	GetLoadBalancer(name string) (*serverscom.L7LoadBalancer, error)
+	LoadBalancerNames(ingress *networkv1.Ingress) []string
+	TrackIngress(ingress *networkv1.Ingress)
 }
 
 // Manager represents a load balancer manager
 type Manager struct {
 	resources map[string]*LoadBalancer
+	// adoptions contains existing load balancers requested by ingresses, keyed by resource name
+	adoptions map[string]adoption
+	// names contains names of registered standalone ingress load balancers, keyed by ingress uid
+	names map[types.UID]string
+	// reclaimPolicy is a default reclaim policy of load balancers
+	reclaimPolicy string
+	// nameTemplate renders names of standalone ingress load balancers, default naming is used if nil
+	nameTemplate *NameTemplate
+	// sslRedirect is a default of HTTP to HTTPS redirect for TLS hosts
+	sslRedirect bool
+
+	lock     sync.Mutex
+	client   *serverscom.Client
+	store    store.Storer
+	recorder record.EventRecorder
+}
 
-	lock   sync.Mutex
-	client *serverscom.Client
-	store  store.Storer
+// adoption describes an existing load balancer which should be taken over,
+// balancer without id is found by name
+type adoption struct {
+	id        string
+	confirmed bool
 }
 
 // NewManager creates a load balancer manager
-func NewManager(client *serverscom.Client, store store.Storer) *Manager {
+func NewManager(client *serverscom.Client, store store.Storer, recorder record.EventRecorder, reclaimPolicy string, nameTemplate *NameTemplate, sslRedirect bool) *Manager {
 	return &Manager{
-		resources: make(map[string]*LoadBalancer),
-		client:    client,
-		store:     store,
+		resources:     make(map[string]*LoadBalancer),
+		adoptions:     make(map[string]adoption),
+		names:         make(map[types.UID]string),
+		reclaimPolicy: reclaimPolicy,
+		nameTemplate:  nameTemplate,
+		sslRedirect:   sslRedirect,
+		client:        client,
+		store:         store,
+		recorder:      recorder,
 	}
 }
 
@@ -62,7 +92,17 @@ func (m *Manager) NewLoadBalancer(input *serverscom.L7LoadBalancerCreateInput) (
 	defer m.lock.Unlock()
 
 	lb := NewLoadBalancer(m.client.LoadBalancers, input)
-	lb.Find(input.Name)
+	a := m.adoptions[input.Name]
+	if a.id != "" {
+		if err := lb.Adopt(a.id, a.confirmed); err != nil {
+			return nil, err, false
+		}
+	} else if findLoadBalancer(lb, input) {
+		// balancer named before naming was changed is renamed in place
+		if err := lb.Claim(a.confirmed); err != nil {
+			return nil, err, false
+		}
+	}
 	l7, err := lb.Sync()
 
 	if err != nil {
@@ -73,7 +113,8 @@ func (m *Manager) NewLoadBalancer(input *serverscom.L7LoadBalancerCreateInput) (
 	return l7, nil, true
 }
 
-// DeleteLoadBalancer deletes load balancer from portal and manager
+// DeleteLoadBalancer deletes load balancer from manager, load balancer is deleted from portal
+// or released depending on its reclaim policy
 func (m *Manager) DeleteLoadBalancer(name string) error {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -84,7 +125,19 @@ func (m *Manager) DeleteLoadBalancer(name string) error {
 		return fmt.Errorf("can't find resource: %s", name)
 	}
 
-	lb.MarkAsDeleted()
+	policy := lb.ReclaimPolicy()
+	if policy == "" {
+		policy = m.reclaimPolicy
+		if lb.adopted {
+			policy = annotations.ReclaimPolicyRetain
+		}
+	}
+
+	if policy == annotations.ReclaimPolicyRetain {
+		lb.MarkAsReleased()
+	} else {
+		lb.MarkAsDeleted()
+	}
 
 	_, err := lb.Sync()
 
@@ -93,6 +146,12 @@ func (m *Manager) DeleteLoadBalancer(name string) error {
 	}
 
 	delete(m.resources, name)
+	delete(m.adoptions, name)
+	for uid, n := range m.names {
+		if n == name {
+			delete(m.names, uid)
+		}
+	}
 
 	return nil
 }
@@ -112,6 +171,10 @@ func (m *Manager) UpdateLoadBalancer(input *serverscom.L7LoadBalancerUpdateInput
 		return lb.state, nil, false
 	}
 
+	if lb.adopted {
+		input.Labels = adoptedLabels(input.Labels)
+	}
+
 	if !lb.IsChanged(input) {
 		return lb.state, nil, false
 	}
@@ -155,7 +218,18 @@ func (m *Manager) TranslateIngressToLB(ingress *networkv1.Ingress, sslCerts map[
 
 	upstreamMap := make(map[string]serverscom.L7UpstreamZoneInput)
 
-	for host, hInfo := range hostsInfo {
+	// sort hosts to get the same input on every sync
+	hosts := make([]string, 0, len(hostsInfo))
+	for host := range hostsInfo {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	sslRedirect := annotations.GetSSLRedirect(ingress.Annotations, m.sslRedirect)
+	canaryPaths := m.getCanaryPaths(ingress)
+
+	for _, host := range hosts {
+		hInfo := hostsInfo[host]
 		var locationZones []serverscom.L7LocationZoneInput
 		vhostPorts := []int32{80}
 		sslEnabled := false
@@ -164,62 +238,182 @@ func (m *Manager) TranslateIngressToLB(ingress *networkv1.Ingress, sslCerts map[
 		if id, ok := sslCerts[host]; ok {
 			sslId = id
 			sslEnabled = true
-			vhostPorts = []int32{443}
+			vhostPorts = []int32{80, 443}
 		}
 
-		vhostAnnotations := make(map[string]string)
-		for _, p := range hInfo.Paths {
+		// http2Required is set if some upstream of host is a gRPC one, gRPC clients need HTTP/2
+		http2Required := false
+		servicesAnnotations := make(map[string]map[string]string)
+		hostUpstreams := make(map[string]store.PathInfo)
+		hostCanaries := make(map[string]canaryPath)
+		locationIndex := make(map[string]int)
+		// exactLocations marks locations of Exact paths, they take precedence over Prefix ones
+		exactLocations := make(map[string]bool)
+		// default backend catches paths which don't match rules of the host
+		paths := hInfo.Paths
+		if host != store.DefaultBackendHost {
+			for _, p := range hostsInfo[store.DefaultBackendHost].Paths {
+				if p.DefaultBackend {
+					paths = append(paths, p)
+				}
+			}
+		}
+		for _, p := range paths {
+			locations, err := GetPathLocations(p.Path, p.PathType)
+			if err != nil {
+				m.recorder.Eventf(ingress, corev1.EventTypeWarning, "InvalidPath", "host %s: %v, path is ignored", host, err)
+				continue
+			}
+
 			upstreamId := fmt.Sprintf("upstream-zone-%s-%d", p.Service.Name, p.NodePort)
+			if c, ok := canaryPaths[store.HostPath{Host: host, Path: p.Path}]; ok && !p.DefaultBackend {
+				stableProtocol := annotations.GetBackendProtocol(p.Service.Annotations, getAppProtocol(p.Service, p.NodePort))
+				canaryProtocol := annotations.GetBackendProtocol(c.Service.Annotations, getAppProtocol(c.Service, c.NodePort))
+				if stableProtocol != canaryProtocol {
+					m.recorder.Eventf(ingress, corev1.EventTypeWarning, "Canary", "host %s path %s: canary service %s uses %s protocol instead of %s, canary is ignored",
+						host, p.Path, c.Service.Name, canaryProtocol, stableProtocol)
+				} else {
+					upstreamId = getCanaryUpstreamID(p, c.PathInfo)
+					hostCanaries[upstreamId] = c
+				}
+			}
+			isExactType := p.PathType != nil && *p.PathType == networkv1.PathTypeExact
+
+			for _, location := range locations {
+				i, ok := locationIndex[location]
+				if !ok {
+					locationIndex[location] = len(locationZones)
+					exactLocations[location] = isExactType
+					locationZones = append(locationZones, serverscom.L7LocationZoneInput{
+						Location:   location,
+						UpstreamID: upstreamId,
+					})
+					continue
+				}
+				if isExactType && !exactLocations[location] {
+					exactLocations[location] = true
+					locationZones[i].UpstreamID = upstreamId
+				}
+			}
+
+			if _, ok := hostUpstreams[upstreamId]; !ok {
+				hostUpstreams[upstreamId] = p
+			}
+		}
+
+		if len(locationZones) == 0 {
+			continue
+		}
+
+		// restricted hosts aren't served to not expose them to all clients
+		var hostServices []*corev1.Service
+		for _, lz := range locationZones {
+			hostServices = append(hostServices, hostUpstreams[lz.UpstreamID].Service)
+		}
+		if err := ValidateSourceRanges(host, ingress.Annotations, hostServices); err != nil {
+			m.recorder.Eventf(ingress, corev1.EventTypeWarning, "SourceRange", "host %s: %v, host is skipped", host, err)
+			continue
+		}
 
-			locationZones = append(locationZones, serverscom.L7LocationZoneInput{
-				Location:   p.Path,
-				UpstreamID: upstreamId,
-			})
+		if ports := annotations.GetListenPorts(host, ingress.Annotations); ports != nil {
+			if err := ValidateListenPorts(ports, sslEnabled, sslEnabled && sslRedirect); err != nil {
+				m.recorder.Eventf(ingress, corev1.EventTypeWarning, "InvalidListenPorts", "host %s: %v, default ports are used", host, err)
+			} else {
+				vhostPorts = ports
+			}
+		}
 
+		for _, lz := range locationZones {
+			upstreamId := lz.UpstreamID
+			p := hostUpstreams[upstreamId]
+			// default backend is added to every host, so it doesn't take part in the host settings
+			if !p.DefaultBackend || host == store.DefaultBackendHost {
+				servicesAnnotations[p.Service.Name] = p.Service.Annotations
+			}
+			protocol := annotations.GetBackendProtocol(p.Service.Annotations, getAppProtocol(p.Service, p.NodePort))
+			if annotations.IsGRPCBackendProtocol(protocol) {
+				http2Required = true
+			}
 			if _, ok := upstreamMap[upstreamId]; !ok {
-				var ups []serverscom.L7UpstreamInput
-				for _, ip := range p.NodeIps {
-					ups = append(ups, serverscom.L7UpstreamInput{
-						IP:     ip,
-						Port:   int32(p.NodePort),
-						Weight: 1,
-					})
+				ups := reduceWeights(getUpstreams(p, 1))
+				if c, ok := hostCanaries[upstreamId]; ok {
+					ups = getCanaryUpstreams(p, c)
 				}
 				upstream := serverscom.L7UpstreamZoneInput{
 					ID:        upstreamId,
 					Upstreams: ups,
 				}
+				// health check annotations take precedence over readiness probes
+				ApplyProbeHealthCheck(&upstream, p, protocol, m.store.GetServicePods(p.Service))
 				upstream = *annotations.FillLBUpstreamZoneWithServiceAnnotations(&upstream, p.Service.Annotations)
+				upstream = *annotations.FillLBUpstreamZoneWithBackendProtocol(&upstream, protocol)
+				if err := annotations.CheckAppProtocol(p.Service.Annotations, getAppProtocol(p.Service, p.NodePort)); err != nil {
+					m.recorder.Eventf(ingress, corev1.EventTypeWarning, "BackendProtocol", "service %s: %v", p.Service.Name, err)
+				}
+				for _, w := range ApplySessionAffinity(&upstream, p.Service) {
+					m.recorder.Eventf(ingress, corev1.EventTypeWarning, "SessionAffinity", "service %s: %s", p.Service.Name, w)
+				}
+				for _, k := range annotations.GetUnsupportedAnnotations(p.Service.Annotations) {
+					m.recorder.Eventf(ingress, corev1.EventTypeWarning, "UnsupportedAnnotation", "service %s: %s isn't supported by the L7 load balancer, annotation is ignored", p.Service.Name, k)
+				}
 				upstreamMap[upstreamId] = upstream
 			}
+		}
 
-			// last-win strategy for vhost annotations
-			for k, v := range p.Service.Annotations {
-				vhostAnnotations[k] = v
+		// host scoped ingress annotations override service annotations
+		hostAnnotations := annotations.GetHostVHostAnnotations(host, ingress.Annotations)
+		vhostAnnotations, conflicts := annotations.MergeVHostAnnotations(servicesAnnotations)
+		for _, c := range conflicts {
+			if _, ok := hostAnnotations[c.Key]; ok {
+				continue
 			}
+			m.recorder.Eventf(ingress, corev1.EventTypeWarning, "AnnotationConflict", "host %s: %s, annotation is ignored", host, c.Error())
+		}
+		for k, v := range hostAnnotations {
+			vhostAnnotations[k] = v
 		}
 
+		vhostID := fmt.Sprintf("vhost-zone-%s", host)
+		domain := host
+		if host == store.DefaultBackendHost {
+			vhostID = DefaultVHostZoneID
+			domain = DefaultVHostDomain
+		}
 		vz := serverscom.L7VHostZoneInput{
-			ID:            fmt.Sprintf("vhost-zone-%s", host),
-			Domains:       []string{host},
-			SSLCertID:     sslId,
-			SSL:           sslEnabled,
-			Ports:         vhostPorts,
-			LocationZones: locationZones,
+			ID:                  vhostID,
+			Domains:             []string{domain},
+			SSLCertID:           sslId,
+			SSL:                 sslEnabled,
+			HTTPToHttpsRedirect: sslEnabled && sslRedirect,
+			Ports:               vhostPorts,
+			LocationZones:       locationZones,
 		}
 		vz = *annotations.FillLBVHostZoneWithServiceAnnotations(&vz, vhostAnnotations)
+		if http2Required {
+			vz.HTTP2 = true
+		}
 		vhostZones = append(vhostZones, vz)
 	}
 
+	upstreamIds := make([]string, 0, len(upstreamMap))
+	for id := range upstreamMap {
+		upstreamIds = append(upstreamIds, id)
+	}
+	sort.Strings(upstreamIds)
+
 	var upstreamZones []serverscom.L7UpstreamZoneInput
-	for _, u := range upstreamMap {
-		upstreamZones = append(upstreamZones, u)
+	for _, id := range upstreamIds {
+		upstreamZones = append(upstreamZones, upstreamMap[id])
 	}
 
 	if len(vhostZones) == 0 || len(upstreamZones) == 0 {
 		return nil, errors.New("vhost or upstream can't be empty, can't continue")
 	}
 
+	if err := ValidateVHostPorts(vhostZones); err != nil {
+		return nil, err
+	}
+
 	locIdStr := config.FetchEnv("SC_LOCATION_ID", "1")
 	locId, err := strconv.Atoi(locIdStr)
 	if err != nil {
@@ -227,17 +421,123 @@ func (m *Manager) TranslateIngressToLB(ingress *networkv1.Ingress, sslCerts map[
 		locId = 1
 	}
 
+	name := m.loadBalancerName(ingress)
+	if annotations.GetLBName(ingress.Annotations) != "" {
+		// ingresses of other classes aren't synced, they don't own names
+		if owner := GetLBNameOwner(ingress, getClassIngresses(ingress, m.store.ListIngress())); owner != nil {
+			return nil, fmt.Errorf("load balancer name %q is used by ingress %s/%s", name, owner.Namespace, owner.Name)
+		}
+	}
+
+	labels := map[string]string{OwnerLabel: name}
+	if annotations.GetLBGroup(ingress.Annotations) == "" {
+		labels[IngressUIDLabel] = string(ingress.UID)
+	}
+	if policy := annotations.GetLBReclaimPolicy(ingress.Annotations); policy != "" {
+		labels[ReclaimPolicyLabel] = policy
+	}
+
 	lbInput := &serverscom.L7LoadBalancerCreateInput{
-		Name:          GetLoadBalancerName(ingress),
+		Name:          name,
 		LocationID:    int64(locId),
 		UpstreamZones: upstreamZones,
 		VHostZones:    vhostZones,
+		Labels:        labels,
 	}
 	lbInput, err = annotations.FillLBWithIngressAnnotations(lbInput, ingress.Annotations)
 
 	return lbInput, err
 }
 
+// LoadBalancerNames returns names of load balancers which belong to ingress:
+// its current name and the registered one while ingress balancer is being renamed
+func (m *Manager) LoadBalancerNames(ingress *networkv1.Ingress) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name := m.loadBalancerName(ingress)
+	if registered, ok := m.names[ingress.UID]; ok && registered != name {
+		return []string{name, registered}
+	}
+	return []string{name}
+}
+
+// TrackIngress remembers load balancer name and adoption request of ingress which is being synced,
+// registered load balancer of renamed ingress is moved to a new name
+func (m *Manager) TrackIngress(ingress *networkv1.Ingress) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	name := m.loadBalancerName(ingress)
+	m.trackName(ingress, name)
+	if id, confirmed := annotations.GetLBAdoption(ingress.Annotations); id != "" || confirmed {
+		m.adoptions[name] = adoption{id: id, confirmed: confirmed}
+	}
+}
+
+// loadBalancerName returns load balancer name of ingress.
+// Group name takes precedence over the name annotation, which takes precedence over name template.
+func (m *Manager) loadBalancerName(ingress *networkv1.Ingress) string {
+	if annotations.GetLBGroup(ingress.Annotations) != "" {
+		return GetLoadBalancerName(ingress)
+	}
+	if name := annotations.GetLBName(ingress.Annotations); name != "" {
+		return name
+	}
+	if m.nameTemplate != nil {
+		name, err := m.nameTemplate.Render(ingress)
+		if err == nil {
+			return name
+		}
+		klog.Errorf("can't render load balancer name of ingress %s/%s: %v", ingress.Namespace, ingress.Name, err)
+	}
+	return GetLoadBalancerName(ingress)
+}
+
+// trackName remembers load balancer name of standalone ingress and moves
+// registered load balancer to a new name, so it's renamed in place on the next update
+func (m *Manager) trackName(ingress *networkv1.Ingress, name string) {
+	if annotations.GetLBGroup(ingress.Annotations) != "" {
+		delete(m.names, ingress.UID)
+		return
+	}
+
+	if registered, ok := m.names[ingress.UID]; ok && registered != name {
+		lb, ok := m.resources[registered]
+		if _, exists := m.resources[name]; ok && !exists {
+			m.resources[name] = lb
+			delete(m.resources, registered)
+			if a, ok := m.adoptions[registered]; ok {
+				m.adoptions[name] = a
+				delete(m.adoptions, registered)
+			}
+			klog.V(2).Infof("load balancer %s is renamed to %s", registered, name)
+		}
+	}
+	m.names[ingress.UID] = name
+}
+
+// findLoadBalancer finds existing balancer of input by its name. Balancer of standalone ingress is also
+// looked up by ingress uid label if it was renamed and by default name it had before naming was changed.
+func findLoadBalancer(lb *LoadBalancer, input *serverscom.L7LoadBalancerCreateInput) bool {
+	if lb.Find(input.Name) {
+		return true
+	}
+
+	uid := input.Labels[IngressUIDLabel]
+	if uid == "" {
+		return false
+	}
+	if lb.FindByLabel(IngressUIDLabel, uid) {
+		return true
+	}
+	if defaultName := defaultLoadBalancerName(uid); defaultName != input.Name && lb.Find(defaultName) {
+		lb.formerName = defaultName
+		return true
+	}
+	return false
+}
+
 // GetLoadBalancer get load balancer from api
 func (m *Manager) GetLoadBalancer(name string) (*serverscom.L7LoadBalancer, error) {
 	m.lock.Lock()
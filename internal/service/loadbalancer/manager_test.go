@@ -1,6 +1,8 @@
 package loadbalancer
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"testing"
@@ -15,12 +17,14 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestHasRegistration(t *testing.T) {
 	g := NewWithT(t)
 
-	manager := NewManager(nil, nil)
+	manager := NewManager(nil, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
 
 	lbName := "test-lb"
 	manager.resources[lbName] = &LoadBalancer{
@@ -52,11 +56,12 @@ func TestNewLoadBalancer(t *testing.T) {
 	lbID := "test-id"
 	startedTime := time.Now()
 	expectedL7LB := &serverscom.L7LoadBalancer{ID: lbID, Name: lbName}
+	sharedCluster := true
 
 	client := serverscom.NewClientWithEndpoint("", "")
 	client.LoadBalancers = lbHandler
 
-	manager := NewManager(client, nil)
+	manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
 	t.Run("Load balancer already exists", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -65,7 +70,10 @@ func TestNewLoadBalancer(t *testing.T) {
 			Return([]serverscom.LoadBalancer{{ID: lbID, Name: lbName}}, nil)
 
 		lbHandler.EXPECT().
-			UpdateL7LoadBalancer(gomock.Any(), lbID, serverscom.L7LoadBalancerUpdateInput{Name: lbName}).
+			GetL7LoadBalancer(gomock.Any(), lbID).
+			Return(&serverscom.L7LoadBalancer{ID: lbID, Name: lbName, Labels: map[string]string{OwnerLabel: lbName}}, nil)
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), lbID, serverscom.L7LoadBalancerUpdateInput{Name: lbName, SharedCluster: &sharedCluster}).
 			Return(expectedL7LB, nil)
 
 		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: lbName})
@@ -95,6 +103,104 @@ func TestNewLoadBalancer(t *testing.T) {
 		g.Expect(manager.resources[lbName].state).To(Equal(expectedL7LB))
 	})
 }
+func TestAdoptLoadBalancer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	lbHandler := mocks.NewMockLoadBalancersService(mockCtrl)
+
+	lbName := "test-lb"
+	lbID := "existing-id"
+	input := &serverscom.L7LoadBalancerCreateInput{
+		Name:   lbName,
+		Labels: map[string]string{OwnerLabel: lbName},
+	}
+	sharedCluster := true
+	updateInput := serverscom.L7LoadBalancerUpdateInput{
+		Name:          lbName,
+		Labels:        map[string]string{OwnerLabel: lbName, AdoptedLabel: "true"},
+		SharedCluster: &sharedCluster,
+	}
+
+	client := serverscom.NewClientWithEndpoint("", "")
+	client.LoadBalancers = lbHandler
+
+	t.Run("Adoption isn't confirmed", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions[lbName] = adoption{id: lbID}
+
+		lbHandler.EXPECT().
+			GetL7LoadBalancer(gomock.Any(), lbID).
+			Return(&serverscom.L7LoadBalancer{ID: lbID, Name: "manual"}, nil)
+
+		_, err, _ := manager.NewLoadBalancer(input)
+
+		g.Expect(err).To(MatchError(ContainSubstring("adoption must be confirmed")))
+		g.Expect(manager.HasRegistration(lbName)).To(BeFalse())
+	})
+
+	t.Run("Load balancer owned by another resource", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions[lbName] = adoption{id: lbID, confirmed: true}
+
+		lbHandler.EXPECT().
+			GetL7LoadBalancer(gomock.Any(), lbID).
+			Return(&serverscom.L7LoadBalancer{ID: lbID, Labels: map[string]string{OwnerLabel: "other-lb"}}, nil)
+
+		_, err, _ := manager.NewLoadBalancer(input)
+
+		g.Expect(err).To(MatchError("load balancer existing-id is owned by other-lb"))
+	})
+
+	t.Run("Confirmed adoption", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions[lbName] = adoption{id: lbID, confirmed: true}
+
+		existing := &serverscom.L7LoadBalancer{ID: lbID, Name: "manual", Labels: map[string]string{"team": "web"}}
+		lbHandler.EXPECT().GetL7LoadBalancer(gomock.Any(), lbID).Return(existing, nil)
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), lbID, updateInput).
+			Return(existing, nil)
+
+		_, err, _ := manager.NewLoadBalancer(input)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources[lbName].id).To(Equal(lbID))
+		g.Expect(manager.resources[lbName].adopted).To(BeTrue())
+
+		// adopted balancer is released instead of being deleted
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), lbID, serverscom.L7LoadBalancerUpdateInput{
+				Labels: map[string]string{OwnerLabel: "", ReleasedLabel: lbName, "team": "web"},
+			}).
+			Return(existing, nil)
+
+		g.Expect(manager.DeleteLoadBalancer(lbName)).To(Succeed())
+		g.Expect(manager.HasRegistration(lbName)).To(BeFalse())
+		g.Expect(manager.adoptions).To(BeEmpty())
+	})
+
+	t.Run("Owned load balancer doesn't need confirmation", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions[lbName] = adoption{id: lbID}
+
+		owned := &serverscom.L7LoadBalancer{ID: lbID, Labels: map[string]string{OwnerLabel: lbName}}
+		lbHandler.EXPECT().GetL7LoadBalancer(gomock.Any(), lbID).Return(owned, nil)
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), lbID, updateInput).
+			Return(owned, nil)
+
+		_, err, _ := manager.NewLoadBalancer(input)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources[lbName].adopted).To(BeTrue())
+	})
+}
+
 func TestUpdateLoadBalancer(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -121,7 +227,7 @@ func TestUpdateLoadBalancer(t *testing.T) {
 	client := serverscom.NewClientWithEndpoint("", "")
 	client.LoadBalancers = lbHandler
 
-	manager := NewManager(client, nil)
+	manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
 	manager.resources[lbName] = &LoadBalancer{
 		id:           lbID,
 		state:        expectedL7LB,
@@ -187,7 +293,7 @@ func TestDeleteLoadBalancer(t *testing.T) {
 
 	client := serverscom.NewClientWithEndpoint("", "")
 	client.LoadBalancers = lbHandler
-	manager := NewManager(client, nil)
+	manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
 	manager.resources[lbName] = &LoadBalancer{
 		id:        lbID,
 		state:     expectedL7LB,
@@ -222,9 +328,304 @@ func TestDeleteLoadBalancer(t *testing.T) {
 	})
 }
 
+func TestReclaimPolicy(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	lbHandler := mocks.NewMockLoadBalancersService(mockCtrl)
+	lbName := "test-lb"
+	lbID := "test-id"
+	releasedLabels := map[string]string{OwnerLabel: "", ReleasedLabel: lbName}
+
+	client := serverscom.NewClientWithEndpoint("", "")
+	client.LoadBalancers = lbHandler
+
+	newLB := func(labels map[string]string) *LoadBalancer {
+		return &LoadBalancer{
+			id:           lbID,
+			state:        &serverscom.L7LoadBalancer{ID: lbID, Name: lbName},
+			currentInput: &serverscom.L7LoadBalancerUpdateInput{Name: lbName, Labels: labels},
+			lBService:    lbHandler,
+		}
+	}
+
+	t.Run("Retain policy label", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.resources[lbName] = newLB(map[string]string{OwnerLabel: lbName, ReclaimPolicyLabel: annotations.ReclaimPolicyRetain})
+
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), lbID, serverscom.L7LoadBalancerUpdateInput{Labels: releasedLabels}).
+			Return(&serverscom.L7LoadBalancer{ID: lbID}, nil)
+
+		g.Expect(manager.DeleteLoadBalancer(lbName)).To(Succeed())
+		g.Expect(manager.HasRegistration(lbName)).To(BeFalse())
+	})
+
+	t.Run("Retain controller default", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyRetain, nil, false)
+		manager.resources[lbName] = newLB(map[string]string{OwnerLabel: lbName})
+
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), lbID, serverscom.L7LoadBalancerUpdateInput{Labels: releasedLabels}).
+			Return(&serverscom.L7LoadBalancer{ID: lbID}, nil)
+
+		g.Expect(manager.DeleteLoadBalancer(lbName)).To(Succeed())
+	})
+
+	t.Run("Delete policy label overrides controller default", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyRetain, nil, false)
+		manager.resources[lbName] = newLB(map[string]string{OwnerLabel: lbName, ReclaimPolicyLabel: annotations.ReclaimPolicyDelete})
+
+		lbHandler.EXPECT().DeleteL7LoadBalancer(gomock.Any(), lbID).Return(nil)
+
+		g.Expect(manager.DeleteLoadBalancer(lbName)).To(Succeed())
+	})
+
+	t.Run("Released load balancer is adopted without confirmation", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions["new-lb"] = adoption{id: lbID}
+
+		released := &serverscom.L7LoadBalancer{ID: lbID, Labels: releasedLabels}
+		lbHandler.EXPECT().GetL7LoadBalancer(gomock.Any(), lbID).Return(released, nil)
+		lbHandler.EXPECT().UpdateL7LoadBalancer(gomock.Any(), lbID, gomock.Any()).Return(released, nil)
+
+		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: "new-lb"})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources["new-lb"].adopted).To(BeTrue())
+	})
+}
+
+func TestLoadBalancerNames(t *testing.T) {
+	tmpl, err := ParseNameTemplate("{{.Namespace}}-{{.Name}}-{{.UIDHash}}")
+	NewWithT(t).Expect(err).To(BeNil())
+
+	ing := &networkv1.Ingress{ObjectMeta: metav1.ObjectMeta{UID: "123", Namespace: "shop", Name: "front"}}
+	templateName := "shop-front-" + getUIDHash(ing)
+
+	t.Run("Default naming", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(nil, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		g.Expect(manager.LoadBalancerNames(ing)).To(Equal([]string{"ingress-a123"}))
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(nil, nil, nil, annotations.ReclaimPolicyDelete, tmpl, false)
+		g.Expect(manager.LoadBalancerNames(ing)).To(Equal([]string{templateName}))
+
+		named := ing.DeepCopy()
+		named.Annotations = map[string]string{annotations.LBName: "storefront"}
+		g.Expect(manager.LoadBalancerNames(named)).To(Equal([]string{"storefront"}))
+
+		named.Annotations[annotations.LBGroup] = "web"
+		g.Expect(manager.LoadBalancerNames(named)).To(Equal([]string{groupLoadBalancerName("shop", "web")}))
+	})
+
+	t.Run("Registered load balancer is moved to a new name", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(nil, nil, nil, annotations.ReclaimPolicyDelete, tmpl, false)
+		lb := &LoadBalancer{id: "lb-id"}
+		manager.resources[templateName] = lb
+		manager.TrackIngress(ing)
+
+		named := ing.DeepCopy()
+		named.Annotations = map[string]string{annotations.LBName: "storefront", annotations.LBAdopt: "true"}
+		g.Expect(manager.LoadBalancerNames(named)).To(Equal([]string{"storefront", templateName}))
+
+		manager.TrackIngress(named)
+		g.Expect(manager.resources).To(Equal(map[string]*LoadBalancer{"storefront": lb}))
+		g.Expect(manager.adoptions).To(Equal(map[string]adoption{"storefront": {confirmed: true}}))
+		g.Expect(manager.LoadBalancerNames(named)).To(Equal([]string{"storefront"}))
+	})
+}
+
+func TestRenameLoadBalancerInPlace(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	lbHandler := mocks.NewMockLoadBalancersService(mockCtrl)
+	collectionHandler := mocks.NewMockCollection[serverscom.LoadBalancer](mockCtrl)
+	lbHandler.EXPECT().Collection().Return(collectionHandler).AnyTimes()
+
+	client := serverscom.NewClientWithEndpoint("", "")
+	client.LoadBalancers = lbHandler
+
+	labels := map[string]string{OwnerLabel: "shop-front", IngressUIDLabel: "123"}
+	input := &serverscom.L7LoadBalancerCreateInput{Name: "shop-front", Labels: labels}
+	sharedCluster := true
+	updateInput := serverscom.L7LoadBalancerUpdateInput{Name: "shop-front", Labels: labels, SharedCluster: &sharedCluster}
+
+	t.Run("Balancer with default name", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+
+		gomock.InOrder(
+			collectionHandler.EXPECT().SetParam("search_pattern", "shop-front").Return(collectionHandler),
+			collectionHandler.EXPECT().SetParam("type", "l7").Return(collectionHandler),
+			collectionHandler.EXPECT().Collect(gomock.Any()).Return(nil, nil),
+			collectionHandler.EXPECT().SetParam("label_selector", IngressUIDLabel+"=123").Return(collectionHandler),
+			collectionHandler.EXPECT().SetParam("type", "l7").Return(collectionHandler),
+			collectionHandler.EXPECT().Collect(gomock.Any()).Return(nil, nil),
+			collectionHandler.EXPECT().SetParam("search_pattern", "ingress-a123").Return(collectionHandler),
+			collectionHandler.EXPECT().SetParam("type", "l7").Return(collectionHandler),
+			collectionHandler.EXPECT().Collect(gomock.Any()).Return([]serverscom.LoadBalancer{{ID: "lb-id", Name: "ingress-a123"}}, nil),
+		)
+		lbHandler.EXPECT().
+			GetL7LoadBalancer(gomock.Any(), "lb-id").
+			Return(&serverscom.L7LoadBalancer{ID: "lb-id", Name: "ingress-a123", Labels: map[string]string{OwnerLabel: "ingress-a123"}}, nil)
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), "lb-id", updateInput).
+			Return(&serverscom.L7LoadBalancer{ID: "lb-id", Name: "shop-front"}, nil)
+
+		l7, err, _ := manager.NewLoadBalancer(input)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(l7.Name).To(Equal("shop-front"))
+		g.Expect(manager.resources["shop-front"].id).To(Equal("lb-id"))
+	})
+
+	t.Run("Balancer is found by ingress uid after restart", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+
+		gomock.InOrder(
+			collectionHandler.EXPECT().SetParam("search_pattern", "shop-front").Return(collectionHandler),
+			collectionHandler.EXPECT().SetParam("type", "l7").Return(collectionHandler),
+			collectionHandler.EXPECT().Collect(gomock.Any()).Return(nil, nil),
+			collectionHandler.EXPECT().SetParam("label_selector", IngressUIDLabel+"=123").Return(collectionHandler),
+			collectionHandler.EXPECT().SetParam("type", "l7").Return(collectionHandler),
+			collectionHandler.EXPECT().Collect(gomock.Any()).Return([]serverscom.LoadBalancer{
+				{ID: "lb-id", Name: "storefront", Labels: map[string]string{OwnerLabel: "storefront", IngressUIDLabel: "123"}},
+			}, nil),
+		)
+		lbHandler.EXPECT().
+			GetL7LoadBalancer(gomock.Any(), "lb-id").
+			Return(&serverscom.L7LoadBalancer{ID: "lb-id", Name: "storefront", Labels: map[string]string{OwnerLabel: "storefront", IngressUIDLabel: "123"}}, nil)
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), "lb-id", updateInput).
+			Return(&serverscom.L7LoadBalancer{ID: "lb-id", Name: "shop-front"}, nil)
+
+		_, err, _ := manager.NewLoadBalancer(input)
+
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources["shop-front"].id).To(Equal("lb-id"))
+		g.Expect(manager.resources["shop-front"].adopted).To(BeFalse())
+	})
+}
+
+func TestClaimLoadBalancer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	lbHandler := mocks.NewMockLoadBalancersService(mockCtrl)
+	collectionHandler := mocks.NewMockCollection[serverscom.LoadBalancer](mockCtrl)
+	lbHandler.EXPECT().Collection().Return(collectionHandler).AnyTimes()
+	collectionHandler.EXPECT().SetParam(gomock.Any(), gomock.Any()).Return(collectionHandler).AnyTimes()
+
+	client := serverscom.NewClientWithEndpoint("", "")
+	client.LoadBalancers = lbHandler
+
+	findByName := func(name string, labels map[string]string) {
+		collectionHandler.EXPECT().Collect(gomock.Any()).Return([]serverscom.LoadBalancer{{ID: "lb-id", Name: name}}, nil)
+		lbHandler.EXPECT().
+			GetL7LoadBalancer(gomock.Any(), "lb-id").
+			Return(&serverscom.L7LoadBalancer{ID: "lb-id", Name: name, Labels: labels}, nil)
+	}
+
+	t.Run("Balancer owned by another resource", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions["shop"] = adoption{confirmed: true}
+		findByName("shop", map[string]string{OwnerLabel: "other-lb"})
+
+		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: "shop"})
+
+		g.Expect(err).To(MatchError("load balancer lb-id is owned by other-lb"))
+		g.Expect(manager.HasRegistration("shop")).To(BeFalse())
+	})
+
+	t.Run("Unlabeled balancer isn't taken over without confirmation", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		findByName("shop", nil)
+
+		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: "shop"})
+
+		g.Expect(err).To(MatchError(ContainSubstring("adoption must be confirmed")))
+		g.Expect(manager.HasRegistration("shop")).To(BeFalse())
+	})
+
+	t.Run("Unlabeled balancer is adopted with confirmation", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		manager.adoptions["shop"] = adoption{confirmed: true}
+		findByName("shop", nil)
+		lbHandler.EXPECT().UpdateL7LoadBalancer(gomock.Any(), "lb-id", gomock.Any()).Return(&serverscom.L7LoadBalancer{ID: "lb-id"}, nil)
+
+		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: "shop"})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources["shop"].adopted).To(BeTrue())
+	})
+
+	t.Run("Unlabeled balancer with default name is owned", func(t *testing.T) {
+		g := NewWithT(t)
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		name := "ingress-a0123456789abcdef0123456789abcde"
+		findByName(name, nil)
+		lbHandler.EXPECT().UpdateL7LoadBalancer(gomock.Any(), "lb-id", gomock.Any()).Return(&serverscom.L7LoadBalancer{ID: "lb-id"}, nil)
+
+		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: name})
+
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources[name].adopted).To(BeFalse())
+	})
+
+	t.Run("Adopted balancer is released after restart", func(t *testing.T) {
+		g := NewWithT(t)
+		// balancer was adopted by name before restart, it's labeled as owned and adopted
+		manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
+		adoptedLabels := map[string]string{OwnerLabel: "shop", AdoptedLabel: "true"}
+		findByName("shop", adoptedLabels)
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), "lb-id", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, input serverscom.L7LoadBalancerUpdateInput) (*serverscom.L7LoadBalancer, error) {
+				g.Expect(input.Labels).To(HaveKeyWithValue(AdoptedLabel, "true"))
+				return &serverscom.L7LoadBalancer{ID: "lb-id", Name: "shop", Labels: adoptedLabels}, nil
+			})
+
+		_, err, _ := manager.NewLoadBalancer(&serverscom.L7LoadBalancerCreateInput{Name: "shop", Labels: map[string]string{OwnerLabel: "shop"}})
+		g.Expect(err).To(BeNil())
+		g.Expect(manager.resources["shop"].adopted).To(BeTrue())
+
+		// later updates keep the label
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), "lb-id", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ string, input serverscom.L7LoadBalancerUpdateInput) (*serverscom.L7LoadBalancer, error) {
+				g.Expect(input.Labels).To(HaveKeyWithValue(AdoptedLabel, "true"))
+				return &serverscom.L7LoadBalancer{ID: "lb-id", Name: "shop", Labels: adoptedLabels}, nil
+			})
+		_, err, _ = manager.UpdateLoadBalancer(&serverscom.L7LoadBalancerUpdateInput{Name: "shop", Labels: map[string]string{OwnerLabel: "shop"}})
+		g.Expect(err).To(BeNil())
+
+		// the balancer is released instead of being deleted with the default Delete policy
+		lbHandler.EXPECT().
+			UpdateL7LoadBalancer(gomock.Any(), "lb-id", serverscom.L7LoadBalancerUpdateInput{
+				Labels: map[string]string{OwnerLabel: "", ReleasedLabel: "shop", AdoptedLabel: "true"},
+			}).
+			Return(&serverscom.L7LoadBalancer{ID: "lb-id"}, nil)
+		g.Expect(manager.DeleteLoadBalancer("shop")).To(Succeed())
+	})
+}
+
 func TestGetIds(t *testing.T) {
 	g := NewGomegaWithT(t)
-	manager := NewManager(nil, nil)
+	manager := NewManager(nil, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
 	ids := manager.GetIds()
 	g.Expect(ids).To(BeEmpty())
 
@@ -313,7 +714,10 @@ func TestTranslateIngressToLB(t *testing.T) {
 
 	client := serverscom.NewClientWithEndpoint("", "")
 	client.LoadBalancers = lbHandler
-	manager := NewManager(client, storeHandler)
+	recorder := record.NewFakeRecorder(10)
+	manager := NewManager(client, storeHandler, recorder, annotations.ReclaimPolicyDelete, nil, false)
+	storeHandler.EXPECT().GetHostPathIngresses(gomock.Any()).Return(nil).AnyTimes()
+	storeHandler.EXPECT().GetServicePods(gomock.Any()).Return(nil).AnyTimes()
 
 	t.Run("Translate ingress to lb input successfully", func(t *testing.T) {
 		g := NewWithT(t)
@@ -323,6 +727,8 @@ func TestTranslateIngressToLB(t *testing.T) {
 		g.Expect(lbInput).NotTo(BeNil())
 
 		g.Expect(lbInput.VHostZones).To(HaveLen(2))
+		g.Expect(lbInput.VHostZones[0].Domains).To(Equal([]string{"example.com"}))
+		g.Expect(lbInput.VHostZones[1].Domains).To(Equal([]string{"foo.com"}))
 
 		for _, vz := range lbInput.VHostZones {
 			expectedID := fmt.Sprintf("vhost-zone-%s", vz.Domains[0])
@@ -354,6 +760,9 @@ func TestTranslateIngressToLB(t *testing.T) {
 		}
 
 		g.Expect(lbInput.UpstreamZones).To(HaveLen(3))
+		g.Expect(lbInput.UpstreamZones[0].ID).To(Equal("upstream-zone-service-foo-30002"))
+		g.Expect(lbInput.UpstreamZones[1].ID).To(Equal("upstream-zone-service-key-30000"))
+		g.Expect(lbInput.UpstreamZones[2].ID).To(Equal("upstream-zone-service-key2-30001"))
 		upstreamIDs := make(map[string]struct{})
 		for _, uz := range lbInput.UpstreamZones {
 			if expected, ok := expectedAlgorithmMethods[uz.ID]; ok {
@@ -379,6 +788,507 @@ func TestTranslateIngressToLB(t *testing.T) {
 		g.Expect(*lbInput.Geoip).To(Equal(true))
 	})
 
+	t.Run("Conflicting service annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		newService := func(name, protocol string) *corev1.Service {
+			return &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: name,
+					Annotations: map[string]string{
+						annotations.AppProtocol: protocol,
+						annotations.LBIPHeader:  "real_ip",
+					},
+				},
+			}
+		}
+		conflictHostsInfo := map[string]store.HostInfo{
+			"example.com": {
+				Paths: []store.PathInfo{
+					{Path: "/a", NodePort: 30000, NodeIps: []string{"192.168.1.1"}, Service: newService("service-a", "http2")},
+					{Path: "/b", NodePort: 30001, NodeIps: []string{"192.168.1.1"}, Service: newService("service-b", "http")},
+				},
+			},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(conflictHostsInfo, nil)
+		lbInput, err := manager.TranslateIngressToLB(ingress, sslCerts)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones).To(HaveLen(1))
+		g.Expect(lbInput.VHostZones[0].HTTP2).To(BeFalse())
+		g.Expect(lbInput.VHostZones[0].RealIPHeader.Name).To(Equal(serverscom.RealIP))
+
+		select {
+		case e := <-recorder.Events:
+			expectedEvent := `Warning AnnotationConflict host example.com: annotation servers.com/app-protocol has conflicting values on services: service-a="http2", service-b="http", annotation is ignored`
+			g.Expect(e).To(Equal(expectedEvent))
+		case <-time.After(time.Second * 1):
+			t.Fatal("Timeout waiting for event")
+		}
+	})
+
+	t.Run("Host scoped annotations override service annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		hostIngress := ingress.DeepCopy()
+		hostIngress.Annotations = map[string]string{
+			annotations.VHostAnnotation("example.com", annotations.VHostHTTP2):        "true",
+			annotations.VHostAnnotation("foo.com", annotations.VHostRealIPHeader):     "forwarded_for",
+			annotations.VHostAnnotation("unknown.com", annotations.VHostRealIPHeader): "real_ip",
+		}
+		newService := func(name, protocol string) *corev1.Service {
+			return &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Annotations: map[string]string{annotations.AppProtocol: protocol},
+				},
+			}
+		}
+		hostHostsInfo := map[string]store.HostInfo{
+			"example.com": {
+				Paths: []store.PathInfo{
+					{Path: "/a", NodePort: 30000, NodeIps: []string{"192.168.1.1"}, Service: newService("service-a", "http2")},
+					{Path: "/b", NodePort: 30001, NodeIps: []string{"192.168.1.1"}, Service: newService("service-b", "http")},
+				},
+			},
+			"foo.com": {
+				Paths: []store.PathInfo{
+					{Path: "/", NodePort: 30001, NodeIps: []string{"192.168.1.1"}, Service: newService("service-b", "http")},
+				},
+			},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(hostIngress).Return(hostHostsInfo, nil)
+		lbInput, err := manager.TranslateIngressToLB(hostIngress, sslCerts)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones).To(HaveLen(2))
+		g.Expect(lbInput.VHostZones[0].HTTP2).To(BeTrue())
+		g.Expect(lbInput.VHostZones[0].RealIPHeader).To(BeNil())
+		g.Expect(lbInput.VHostZones[1].HTTP2).To(BeFalse())
+		g.Expect(lbInput.VHostZones[1].RealIPHeader.Name).To(Equal(serverscom.ForwardedFor))
+		g.Expect(recorder.Events).To(BeEmpty())
+	})
+
+	t.Run("Path types", func(t *testing.T) {
+		g := NewWithT(t)
+		exact := networkv1.PathTypeExact
+		prefix := networkv1.PathTypePrefix
+		newService := func(name string) *corev1.Service {
+			return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		}
+		pathHostsInfo := map[string]store.HostInfo{
+			"example.com": {
+				Paths: []store.PathInfo{
+					{Path: "/foo", PathType: &prefix, NodePort: 30000, NodeIps: []string{"192.168.1.1"}, Service: newService("service-a")},
+					{Path: "/foo", PathType: &exact, NodePort: 30001, NodeIps: []string{"192.168.1.1"}, Service: newService("service-b")},
+					{Path: "/bar baz", PathType: &prefix, NodePort: 30002, NodeIps: []string{"192.168.1.1"}, Service: newService("service-c")},
+				},
+			},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(pathHostsInfo, nil)
+		lbInput, err := manager.TranslateIngressToLB(ingress, sslCerts)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones).To(HaveLen(1))
+		g.Expect(lbInput.VHostZones[0].LocationZones).To(Equal([]serverscom.L7LocationZoneInput{
+			{Location: "= /foo", UpstreamID: "upstream-zone-service-b-30001"},
+			{Location: "/foo/", UpstreamID: "upstream-zone-service-a-30000"},
+		}))
+		g.Expect(lbInput.UpstreamZones).To(HaveLen(2))
+
+		select {
+		case e := <-recorder.Events:
+			expectedEvent := `Warning InvalidPath host example.com: path "/bar baz" contains characters which can't be used in a location, path is ignored`
+			g.Expect(e).To(Equal(expectedEvent))
+		case <-time.After(time.Second * 1):
+			t.Fatal("Timeout waiting for event")
+		}
+	})
+
+	t.Run("Default backend", func(t *testing.T) {
+		g := NewWithT(t)
+		prefix := networkv1.PathTypePrefix
+		implSpecific := networkv1.PathTypeImplementationSpecific
+		newService := func(name string) *corev1.Service {
+			return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		}
+		defaultHostsInfo := map[string]store.HostInfo{
+			store.DefaultBackendHost: {
+				Paths: []store.PathInfo{
+					{Path: "/api", PathType: &prefix, NodePort: 30000, NodeIps: []string{"192.168.1.1"}, Service: newService("service-api")},
+					{Path: "/", PathType: &implSpecific, NodePort: 30001, NodeIps: []string{"192.168.1.1"}, Service: newService("service-default"), DefaultBackend: true},
+				},
+			},
+			"example.com": {
+				Paths: []store.PathInfo{
+					{Path: "/app", PathType: &prefix, NodePort: 30002, NodeIps: []string{"192.168.1.1"}, Service: newService("service-app")},
+				},
+			},
+			"foo.com": {
+				Paths: []store.PathInfo{
+					{Path: "/", PathType: &prefix, NodePort: 30002, NodeIps: []string{"192.168.1.1"}, Service: newService("service-app")},
+				},
+			},
+		}
+		// default backend settings don't conflict with settings of hosts
+		defaultHostsInfo[store.DefaultBackendHost].Paths[1].Service.Annotations = map[string]string{annotations.AppProtocol: "http2"}
+		defaultHostsInfo["example.com"].Paths[0].Service.Annotations = map[string]string{annotations.AppProtocol: "http"}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(defaultHostsInfo, nil)
+		lbInput, err := manager.TranslateIngressToLB(ingress, sslCerts)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones).To(HaveLen(3))
+		g.Expect(recorder.Events).To(BeEmpty())
+
+		g.Expect(lbInput.VHostZones[0].ID).To(Equal(DefaultVHostZoneID))
+		g.Expect(lbInput.VHostZones[0].Domains).To(Equal([]string{DefaultVHostDomain}))
+		g.Expect(lbInput.VHostZones[0].HTTP2).To(BeTrue())
+		g.Expect(lbInput.VHostZones[0].LocationZones).To(Equal([]serverscom.L7LocationZoneInput{
+			{Location: "= /api", UpstreamID: "upstream-zone-service-api-30000"},
+			{Location: "/api/", UpstreamID: "upstream-zone-service-api-30000"},
+			{Location: "/", UpstreamID: "upstream-zone-service-default-30001"},
+		}))
+
+		g.Expect(lbInput.VHostZones[1].Domains).To(Equal([]string{"example.com"}))
+		g.Expect(lbInput.VHostZones[1].HTTP2).To(BeFalse())
+		g.Expect(lbInput.VHostZones[1].LocationZones).To(Equal([]serverscom.L7LocationZoneInput{
+			{Location: "= /app", UpstreamID: "upstream-zone-service-app-30002"},
+			{Location: "/app/", UpstreamID: "upstream-zone-service-app-30002"},
+			{Location: "/", UpstreamID: "upstream-zone-service-default-30001"},
+		}))
+
+		// rule path wins over default backend
+		g.Expect(lbInput.VHostZones[2].Domains).To(Equal([]string{"foo.com"}))
+		g.Expect(lbInput.VHostZones[2].LocationZones).To(Equal([]serverscom.L7LocationZoneInput{
+			{Location: "/", UpstreamID: "upstream-zone-service-app-30002"},
+		}))
+		g.Expect(lbInput.UpstreamZones).To(HaveLen(3))
+	})
+
+	t.Run("Ownership, reclaim policy labels and adoption", func(t *testing.T) {
+		g := NewWithT(t)
+		adopting := ingress.DeepCopy()
+		adopting.Annotations = map[string]string{
+			annotations.LBID:            "existing-id",
+			annotations.LBAdopt:         "true",
+			annotations.LBReclaimPolicy: "Retain",
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(adopting).Return(hostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(adopting, sslCerts)
+		g.Expect(err).To(BeNil())
+
+		name := GetLoadBalancerName(adopting)
+		g.Expect(lbInput.Labels).To(Equal(map[string]string{OwnerLabel: name, IngressUIDLabel: "123", ReclaimPolicyLabel: "Retain"}))
+		// adoption is remembered by sync, translation doesn't change manager state
+		g.Expect(manager.adoptions).To(BeEmpty())
+
+		manager.TrackIngress(adopting)
+		g.Expect(manager.adoptions).To(HaveKeyWithValue(name, adoption{id: "existing-id", confirmed: true}))
+	})
+
+	t.Run("Name used by older ingress", func(t *testing.T) {
+		g := NewWithT(t)
+		named := ingress.DeepCopy()
+		named.CreationTimestamp = metav1.Unix(200, 0)
+		named.Annotations = map[string]string{annotations.LBName: "storefront"}
+		older := named.DeepCopy()
+		older.Name = "old-front"
+		older.CreationTimestamp = metav1.Unix(100, 0)
+		storeHandler.EXPECT().GetIngressHostsInfo(named).Return(hostsInfo, nil)
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{named, older})
+
+		_, err := manager.TranslateIngressToLB(named, sslCerts)
+		g.Expect(err).To(MatchError(`load balancer name "storefront" is used by ingress ` + older.Namespace + "/old-front"))
+
+		// older ingress of another class doesn't own the name
+		otherClass := "other"
+		older.Spec.IngressClassName = &otherClass
+		storeHandler.EXPECT().GetIngressHostsInfo(named).Return(hostsInfo, nil)
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{named, older})
+
+		lbInput, err := manager.TranslateIngressToLB(named, sslCerts)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.Name).To(Equal("storefront"))
+	})
+
+	t.Run("TLS vhosts serve HTTP and HTTPS", func(t *testing.T) {
+		g := NewWithT(t)
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(hostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(ingress, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones).To(HaveLen(2))
+
+		g.Expect(lbInput.VHostZones[0].Domains).To(Equal([]string{"example.com"}))
+		g.Expect(lbInput.VHostZones[0].Ports).To(Equal([]int32{80, 443}))
+		g.Expect(lbInput.VHostZones[0].SSL).To(BeTrue())
+		g.Expect(lbInput.VHostZones[0].HTTPToHttpsRedirect).To(BeFalse())
+
+		g.Expect(lbInput.VHostZones[1].Domains).To(Equal([]string{"foo.com"}))
+		g.Expect(lbInput.VHostZones[1].Ports).To(Equal([]int32{80}))
+		g.Expect(lbInput.VHostZones[1].SSL).To(BeFalse())
+	})
+
+	t.Run("SSL redirect", func(t *testing.T) {
+		g := NewWithT(t)
+		redirecting := ingress.DeepCopy()
+		redirecting.Annotations = map[string]string{annotations.SSLRedirect: "true"}
+		storeHandler.EXPECT().GetIngressHostsInfo(redirecting).Return(hostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(redirecting, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones[0].HTTPToHttpsRedirect).To(BeTrue())
+		// hosts without TLS aren't redirected
+		g.Expect(lbInput.VHostZones[1].HTTPToHttpsRedirect).To(BeFalse())
+
+		defaultRedirect := NewManager(client, storeHandler, recorder, annotations.ReclaimPolicyDelete, nil, true)
+		redirecting.Annotations[annotations.SSLRedirect] = "false"
+		storeHandler.EXPECT().GetIngressHostsInfo(redirecting).Return(hostsInfo, nil)
+
+		lbInput, err = defaultRedirect.TranslateIngressToLB(redirecting, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones[0].HTTPToHttpsRedirect).To(BeFalse())
+
+		delete(redirecting.Annotations, annotations.SSLRedirect)
+		storeHandler.EXPECT().GetIngressHostsInfo(redirecting).Return(hostsInfo, nil)
+
+		lbInput, err = defaultRedirect.TranslateIngressToLB(redirecting, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones[0].HTTPToHttpsRedirect).To(BeTrue())
+	})
+
+	t.Run("Listen ports", func(t *testing.T) {
+		g := NewWithT(t)
+		custom := ingress.DeepCopy()
+		custom.Annotations = map[string]string{
+			annotations.ListenPorts: "80,8080",
+			annotations.VHostAnnotation("example.com", annotations.VHostListenPorts): "80,443,8443",
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(custom).Return(hostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(custom, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones[0].Ports).To(Equal([]int32{80, 443, 8443}))
+		g.Expect(lbInput.VHostZones[1].Ports).To(Equal([]int32{80, 8080}))
+
+		// invalid ports of a host are replaced with default ones
+		custom.Annotations[annotations.VHostAnnotation("example.com", annotations.VHostListenPorts)] = "80"
+		storeHandler.EXPECT().GetIngressHostsInfo(custom).Return(hostsInfo, nil)
+
+		lbInput, err = manager.TranslateIngressToLB(custom, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones[0].Ports).To(Equal([]int32{80, 443}))
+		g.Expect(recorder.Events).To(Receive(Equal("Warning InvalidListenPorts host example.com: host with TLS needs a port other than 80, default ports are used")))
+
+		// the same port can't serve HTTP and HTTPS
+		custom.Annotations[annotations.VHostAnnotation("example.com", annotations.VHostListenPorts)] = "8080"
+		storeHandler.EXPECT().GetIngressHostsInfo(custom).Return(hostsInfo, nil)
+
+		_, err = manager.TranslateIngressToLB(custom, map[string]string{"example.com": "ssl-cert-id"})
+		g.Expect(err).To(MatchError("port 8080 is used with and without TLS by hosts example.com and foo.com"))
+	})
+
+	t.Run("Backend protocol", func(t *testing.T) {
+		g := NewWithT(t)
+		grpcService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "service-grpc"},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 80, NodePort: 30010, AppProtocol: &[]string{"grpc"}[0]}},
+			},
+		}
+		httpsService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "service-https",
+				Annotations: map[string]string{annotations.BackendProtocol: "https"},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 443, NodePort: 30011}},
+			},
+		}
+		protocolHostsInfo := map[string]store.HostInfo{
+			"grpc.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30010, NodeIps: []string{"192.168.1.1"}, Service: grpcService},
+			}},
+			"secure.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30011, NodeIps: []string{"192.168.1.1"}, Service: httpsService},
+			}},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(protocolHostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(ingress, nil)
+		g.Expect(err).To(BeNil())
+
+		g.Expect(lbInput.VHostZones).To(HaveLen(2))
+		g.Expect(lbInput.VHostZones[0].HTTP2).To(BeTrue())
+		g.Expect(lbInput.VHostZones[1].HTTP2).To(BeFalse())
+
+		g.Expect(lbInput.UpstreamZones).To(HaveLen(2))
+		g.Expect(lbInput.UpstreamZones[0].ID).To(Equal("upstream-zone-service-grpc-30010"))
+		g.Expect(lbInput.UpstreamZones[0].GRPC).NotTo(BeNil())
+		g.Expect(*lbInput.UpstreamZones[0].GRPC).To(BeTrue())
+		g.Expect(lbInput.UpstreamZones[0].SSL).To(BeFalse())
+		g.Expect(lbInput.UpstreamZones[1].ID).To(Equal("upstream-zone-service-https-30011"))
+		g.Expect(lbInput.UpstreamZones[1].GRPC).To(BeNil())
+		g.Expect(lbInput.UpstreamZones[1].SSL).To(BeTrue())
+	})
+
+	t.Run("Session affinity", func(t *testing.T) {
+		g := NewWithT(t)
+		stickyService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "service-sticky",
+				Annotations: map[string]string{annotations.LBBalancingAlgorithm: "round_robin"},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports:           []corev1.ServicePort{{Port: 80, NodePort: 30020}},
+				SessionAffinity: corev1.ServiceAffinityClientIP,
+			},
+		}
+		stickyHostsInfo := map[string]store.HostInfo{
+			"sticky.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30020, NodeIps: []string{"192.168.1.1"}, Service: stickyService},
+			}},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(stickyHostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(ingress, nil)
+		g.Expect(err).To(BeNil())
+		g.Expect(*lbInput.UpstreamZones[0].Method).To(Equal("round_robin"))
+		g.Expect(recorder.Events).To(Receive(Equal("Warning SessionAffinity service service-sticky: spec.sessionAffinity ClientIP is ignored, balancing algorithm round_robin is set")))
+
+		delete(stickyService.Annotations, annotations.LBBalancingAlgorithm)
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(stickyHostsInfo, nil)
+
+		lbInput, err = manager.TranslateIngressToLB(ingress, nil)
+		g.Expect(err).To(BeNil())
+		g.Expect(*lbInput.UpstreamZones[0].Method).To(Equal("ip_hash"))
+	})
+
+	t.Run("Unsupported service annotations", func(t *testing.T) {
+		g := NewWithT(t)
+		tunedService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "service-tuned",
+				Annotations: map[string]string{annotations.ProxyReadTimeout: "10m"},
+			},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, NodePort: 30040}}},
+		}
+		tunedHostsInfo := map[string]store.HostInfo{
+			"tuned.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30040, NodeIps: []string{"192.168.1.1"}, Service: tunedService},
+			}},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(tunedHostsInfo, nil)
+
+		_, err := manager.TranslateIngressToLB(ingress, nil)
+		g.Expect(err).To(BeNil())
+		g.Expect(recorder.Events).To(Receive(Equal("Warning UnsupportedAnnotation service service-tuned: servers.com/proxy-read-timeout isn't supported by the L7 load balancer, annotation is ignored")))
+	})
+
+	t.Run("H2C app protocol", func(t *testing.T) {
+		g := NewWithT(t)
+		h2cService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "service-h2c"},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 80, NodePort: 30050, AppProtocol: &[]string{"kubernetes.io/h2c"}[0]}},
+			},
+		}
+		h2cHostsInfo := map[string]store.HostInfo{
+			"h2c.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30050, NodeIps: []string{"192.168.1.1"}, Service: h2cService},
+			}},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(h2cHostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(ingress, nil)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones[0].HTTP2).To(BeFalse())
+		g.Expect(lbInput.UpstreamZones[0].GRPC).To(BeNil())
+		g.Expect(recorder.Events).To(Receive(Equal("Warning BackendProtocol service service-h2c: appProtocol kubernetes.io/h2c isn't supported, the balancer speaks HTTP/2 to upstreams only as gRPC transport, HTTP is used")))
+	})
+
+	t.Run("Restricted hosts are skipped", func(t *testing.T) {
+		g := NewWithT(t)
+		restricted := ingress.DeepCopy()
+		restricted.Annotations = map[string]string{
+			annotations.VHostAnnotation("foo.com", annotations.VHostWhitelistSourceRange): "10.0.0.0/8",
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(restricted).Return(hostsInfo, nil)
+
+		lbInput, err := manager.TranslateIngressToLB(restricted, sslCerts)
+		g.Expect(err).To(BeNil())
+		g.Expect(lbInput.VHostZones).To(HaveLen(1))
+		g.Expect(lbInput.VHostZones[0].Domains).To(Equal([]string{"example.com"}))
+		g.Expect(lbInput.UpstreamZones).To(HaveLen(2))
+		g.Expect(recorder.Events).To(Receive(Equal("Warning SourceRange host foo.com: source ranges 10.0.0.0/8 can't be enforced by the L7 load balancer, host is skipped")))
+	})
+
+	t.Run("Canary", func(t *testing.T) {
+		g := NewWithT(t)
+		canaryStore := mocks.NewMockStorer(mockCtrl)
+		canaryStore.EXPECT().GetServicePods(gomock.Any()).Return(nil).AnyTimes()
+		canaryManager := NewManager(client, canaryStore, recorder, annotations.ReclaimPolicyDelete, nil, false)
+
+		stableService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "app"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, NodePort: 30030}}},
+		}
+		canaryService := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-canary"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80, NodePort: 30031}}},
+		}
+		stableHostsInfo := map[string]store.HostInfo{
+			"app.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30030, NodeIps: []string{"192.168.1.1", "192.168.1.2"}, Service: stableService},
+				{Path: "/static", NodePort: 30030, NodeIps: []string{"192.168.1.1", "192.168.1.2"}, Service: stableService},
+			}},
+		}
+		canaryHostsInfo := map[string]store.HostInfo{
+			"app.com": {Paths: []store.PathInfo{
+				{Path: "/", NodePort: 30031, NodeIps: []string{"192.168.1.1", "192.168.1.2"}, Service: canaryService},
+			}},
+		}
+		canary := &networkv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "app-canary",
+				Annotations: map[string]string{annotations.Canary: "true", annotations.CanaryWeight: "20"},
+			},
+		}
+		otherNamespace := canary.DeepCopy()
+		otherNamespace.Namespace = "other"
+
+		translate := func() *serverscom.L7LoadBalancerCreateInput {
+			canaryStore.EXPECT().GetHostPathIngresses(ingress).Return([]*networkv1.Ingress{otherNamespace, canary})
+			canaryStore.EXPECT().GetIngressHostsInfo(canary).Return(canaryHostsInfo, nil)
+			canaryStore.EXPECT().GetIngressHostsInfo(ingress).Return(stableHostsInfo, nil)
+			lbInput, err := canaryManager.TranslateIngressToLB(ingress, nil)
+			g.Expect(err).To(BeNil())
+			return lbInput
+		}
+
+		lbInput := translate()
+		g.Expect(lbInput.UpstreamZones).To(HaveLen(2))
+		g.Expect(lbInput.UpstreamZones[0].ID).To(Equal("upstream-zone-app-30030"))
+		g.Expect(lbInput.UpstreamZones[1].ID).To(Equal("upstream-zone-app-30030-canary-app-canary-30031"))
+		g.Expect(lbInput.UpstreamZones[1].Upstreams).To(Equal([]serverscom.L7UpstreamInput{
+			{IP: "192.168.1.1", Port: 30030, Weight: 4},
+			{IP: "192.168.1.2", Port: 30030, Weight: 4},
+			{IP: "192.168.1.1", Port: 30031, Weight: 1},
+			{IP: "192.168.1.2", Port: 30031, Weight: 1},
+		}))
+		g.Expect(lbInput.VHostZones[0].LocationZones[0].UpstreamID).To(Equal("upstream-zone-app-30030-canary-app-canary-30031"))
+
+		// weight change keeps upstream zone ids
+		canary.Annotations[annotations.CanaryWeight] = "100"
+		lbInput = translate()
+		g.Expect(lbInput.UpstreamZones[1].ID).To(Equal("upstream-zone-app-30030-canary-app-canary-30031"))
+		g.Expect(lbInput.UpstreamZones[1].Upstreams).To(Equal([]serverscom.L7UpstreamInput{
+			{IP: "192.168.1.1", Port: 30031, Weight: 1},
+			{IP: "192.168.1.2", Port: 30031, Weight: 1},
+		}))
+
+		// canary with another backend protocol is ignored
+		canaryService.Annotations = map[string]string{annotations.BackendProtocol: "GRPC"}
+		lbInput = translate()
+		g.Expect(lbInput.UpstreamZones).To(HaveLen(1))
+		g.Expect(recorder.Events).To(Receive(Equal("Warning Canary host app.com path /: canary service app-canary uses GRPC protocol instead of HTTP, canary is ignored")))
+	})
+
 	t.Run("Services info fails", func(t *testing.T) {
 		g := NewWithT(t)
 		storeHandler.EXPECT().GetIngressHostsInfo(ingress).Return(nil, errors.New("error"))
@@ -410,7 +1320,7 @@ func TestGetLoadBalancer(t *testing.T) {
 
 	client := serverscom.NewClientWithEndpoint("", "")
 	client.LoadBalancers = lbHandler
-	manager := NewManager(client, nil)
+	manager := NewManager(client, nil, nil, annotations.ReclaimPolicyDelete, nil, false)
 	manager.resources[lbName] = &LoadBalancer{
 		id:        lbID,
 		state:     expectedL7LB,
@@ -433,3 +1343,69 @@ func TestGetLoadBalancer(t *testing.T) {
 		g.Expect(result).To(BeEquivalentTo(expectedL7LB))
 	})
 }
+
+func FuzzTranslateIngressToLB(f *testing.F) {
+	f.Add([]byte(`{"metadata":{"annotations":{"servers.com/vhost.example.com.http2":"true"}},"spec":{"rules":[{"host":"example.com","http":{"paths":[{"path":"/","pathType":"Prefix","backend":{"service":{"name":"svc-a","port":{"name":"http"}}}}]}}],"tls":[{"hosts":["example.com"],"secretName":"secret"}]}}`))
+	f.Add([]byte(`{"spec":{"rules":[{"http":{"paths":[{"path":"/a b","pathType":"Exact","backend":{"service":{"name":"svc-a","port":{"number":80}}}},{"path":"a","backend":{"service":{"name":"svc-b","port":{"number":80}}}}]}}]}}`))
+	f.Add([]byte(`{"metadata":{"annotations":{"servers.com/load-balancer-geoip-enabled":"yes","servers.com/vhost.":"x"}},"spec":{"rules":[{"host":"foo.com","http":{"paths":[{"path":"/","pathType":"Regex","backend":{"resource":{"kind":"Bucket","name":"static"}}}]}}]}}`))
+
+	// backends are resolved by the store the same way as in the controller
+	fakeClient := fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.1.1"}},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "svc-a", Namespace: "default"},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30000}},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "svc-b",
+				Namespace: "default",
+				Annotations: map[string]string{
+					annotations.LBIPHeader:         "real_ip",
+					annotations.LBIPSubnets:        "10.0.0.0/8",
+					annotations.AppHealthcheckPath: "/health",
+				},
+			},
+			Spec: corev1.ServiceSpec{
+				Ports: []corev1.ServicePort{{Port: 80, NodePort: 30001}},
+			},
+		},
+	)
+	ingressStore := store.NewReadOnly("", 0, fakeClient, "", nil)
+	stopCh := make(chan struct{})
+	f.Cleanup(func() { close(stopCh) })
+	ingressStore.Run(stopCh)
+	manager := NewManager(nil, ingressStore, &record.FakeRecorder{}, annotations.ReclaimPolicyDelete, nil, false)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ingress := &networkv1.Ingress{}
+		if err := json.Unmarshal(data, ingress); err != nil {
+			t.Skip()
+		}
+		ingress.Namespace = "default"
+
+		sslCerts := make(map[string]string)
+		for _, tls := range ingress.Spec.TLS {
+			for _, host := range tls.Hosts {
+				sslCerts[host] = tls.SecretName
+			}
+		}
+
+		lbInput, err := manager.TranslateIngressToLB(ingress, sslCerts)
+		if err != nil {
+			return
+		}
+		for _, vz := range lbInput.VHostZones {
+			if len(vz.LocationZones) == 0 {
+				t.Fatalf("vhost zone %s has no locations", vz.ID)
+			}
+		}
+	})
+}
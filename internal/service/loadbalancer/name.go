@@ -0,0 +1,159 @@
+package loadbalancer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	v1 "k8s.io/api/networking/v1"
+)
+
+const (
+	// MaxNameLength is a max length of load balancer name
+	MaxNameLength = 63
+
+	// uidHashLength is a length of short ingress uid hash
+	uidHashLength = 8
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// NameTemplate renders load balancer names from ingress objects
+type NameTemplate struct {
+	tmpl *template.Template
+}
+
+// nameVars contains variables available in name template
+type nameVars struct {
+	ClusterID string
+	Namespace string
+	Name      string
+	Class     string
+	UIDHash   string
+}
+
+// ParseNameTemplate parses load balancer name template, e.g. "{{.Namespace}}-{{.Name}}-{{.UIDHash}}".
+// Template must use {{.UIDHash}} to produce different names for different ingresses. Returns nil for empty text.
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("name").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid load balancer name template: %v", err)
+	}
+	t := &NameTemplate{tmpl: tmpl}
+
+	// sanitizing maps all separators to dashes, so namespace and name can't identify ingress unambiguously
+	// ("a-b"/"c" and "a"/"b-c"), an ingress recreated with the same name must not reuse a balancer either.
+	// Template must depend on uid hash, samples differ only in it.
+	samples := []nameVars{
+		{ClusterID: "c1", Namespace: "ns1", Name: "ing1", Class: "sc", UIDHash: "00000001"},
+		{ClusterID: "c1", Namespace: "ns1", Name: "ing1", Class: "sc", UIDHash: "00000002"},
+	}
+	names := make(map[string]struct{})
+	for _, vars := range samples {
+		name, err := t.render(vars)
+		if err != nil {
+			return nil, fmt.Errorf("invalid load balancer name template: %v", err)
+		}
+		if _, ok := names[name]; ok {
+			return nil, fmt.Errorf("invalid load balancer name template: names must be unique, use {{.UIDHash}}")
+		}
+		names[name] = struct{}{}
+	}
+
+	return t, nil
+}
+
+// Render renders load balancer name for ingress.
+// Name is sanitized and shortened to MaxNameLength keeping uid hash at the end.
+func (t *NameTemplate) Render(ing *v1.Ingress) (string, error) {
+	return t.render(nameVars{
+		ClusterID: ing.Annotations[annotations.LBClusterID],
+		Namespace: ing.Namespace,
+		Name:      ing.Name,
+		Class:     getIngressClass(ing),
+		UIDHash:   getUIDHash(ing),
+	})
+}
+
+// render executes template and sanitizes the result
+func (t *NameTemplate) render(vars nameVars) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(b.String()), "-")
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return "", fmt.Errorf("template renders empty name")
+	}
+
+	if len(name) > MaxNameLength {
+		name = strings.TrimRight(name[:MaxNameLength-uidHashLength-1], "-") + "-" + vars.UIDHash
+	}
+
+	return name, nil
+}
+
+// GetLBNameOwner returns the oldest standalone ingress older than ing which sets the same
+// load balancer name annotation, the name belongs to it. Returns nil if the name isn't used.
+func GetLBNameOwner(ing *v1.Ingress, ingresses []*v1.Ingress) *v1.Ingress {
+	name := annotations.GetLBName(ing.Annotations)
+	if name == "" || annotations.GetLBGroup(ing.Annotations) != "" {
+		return nil
+	}
+
+	var owner *v1.Ingress
+	for _, other := range ingresses {
+		if other.Namespace == ing.Namespace && other.Name == ing.Name {
+			continue
+		}
+		if annotations.GetLBGroup(other.Annotations) != "" || annotations.IsCanary(other.Annotations) {
+			continue
+		}
+		if annotations.GetLBName(other.Annotations) != name || !store.IsOlderIngress(other, ing) {
+			continue
+		}
+		if owner == nil || store.IsOlderIngress(other, owner) {
+			owner = other
+		}
+	}
+	return owner
+}
+
+// getClassIngresses returns ingresses of the same class as ing
+func getClassIngresses(ing *v1.Ingress, ingresses []*v1.Ingress) []*v1.Ingress {
+	class := getIngressClass(ing)
+	var res []*v1.Ingress
+	for _, other := range ingresses {
+		if ingress.IsScIngress(other, class) {
+			res = append(res, other)
+		}
+	}
+	return res
+}
+
+// getIngressClass returns ingress class from spec or legacy annotation
+func getIngressClass(ing *v1.Ingress) string {
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName
+	}
+	return ing.Annotations[ingress.IngressClassKey]
+}
+
+// getUIDHash returns short hash of ingress uid
+func getUIDHash(ing *v1.Ingress) string {
+	sum := sha256.Sum256([]byte(ing.UID))
+	return hex.EncodeToString(sum[:])[:uidHashLength]
+}
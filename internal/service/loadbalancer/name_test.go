@@ -0,0 +1,120 @@
+package loadbalancer
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseNameTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		err      string
+	}{
+		{name: "Namespace, name and uid hash", template: "{{.Namespace}}-{{.Name}}-{{.UIDHash}}"},
+		{name: "Namespace and name are ambiguous", template: "{{.Namespace}}-{{.Name}}", err: "names must be unique"},
+		{name: "Uid hash", template: "lb-{{.UIDHash}}"},
+		{name: "Invalid syntax", template: "{{.Name", err: "invalid load balancer name template"},
+		{name: "Unknown variable", template: "{{.Unknown}}-{{.UIDHash}}", err: "can't evaluate field Unknown"},
+		{name: "Not unique", template: "{{.Class}}-{{.Name}}", err: "names must be unique"},
+		{name: "Empty name", template: "{{if false}}{{.UIDHash}}{{end}}", err: "template renders empty name"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			tmpl, err := ParseNameTemplate(tc.template)
+			if tc.err != "" {
+				g.Expect(err).To(MatchError(ContainSubstring(tc.err)))
+				return
+			}
+			g.Expect(err).To(BeNil())
+			g.Expect(tmpl).NotTo(BeNil())
+		})
+	}
+
+	t.Run("Empty template", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tmpl, err := ParseNameTemplate("")
+		g.Expect(err).To(BeNil())
+		g.Expect(tmpl).To(BeNil())
+	})
+}
+
+func TestNameTemplateRender(t *testing.T) {
+	class := "serverscom"
+	ing := &v1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:         "1234-5678",
+			Namespace:   "Shop",
+			Name:        "front_end",
+			Annotations: map[string]string{annotations.LBClusterID: "c42"},
+		},
+		Spec: v1.IngressSpec{IngressClassName: &class},
+	}
+
+	t.Run("Variables are rendered and sanitized", func(t *testing.T) {
+		g := NewWithT(t)
+
+		tmpl, err := ParseNameTemplate("{{.ClusterID}}.{{.Class}}.{{.Namespace}}.{{.Name}}.{{.UIDHash}}")
+		g.Expect(err).To(BeNil())
+
+		name, err := tmpl.Render(ing)
+		g.Expect(err).To(BeNil())
+		g.Expect(name).To(Equal("c42-serverscom-shop-front-end-" + getUIDHash(ing)))
+	})
+
+	t.Run("Long name is shortened keeping uid hash", func(t *testing.T) {
+		g := NewWithT(t)
+
+		long := ing.DeepCopy()
+		long.Name = strings.Repeat("a", 100)
+		tmpl, err := ParseNameTemplate("{{.Namespace}}-{{.Name}}-{{.UIDHash}}")
+		g.Expect(err).To(BeNil())
+
+		name, err := tmpl.Render(long)
+		g.Expect(err).To(BeNil())
+		g.Expect(name).To(HaveLen(MaxNameLength))
+		g.Expect(name).To(HaveSuffix("-" + getUIDHash(long)))
+	})
+}
+
+func TestGetLBNameOwner(t *testing.T) {
+	newIngress := func(name string, created int64, ann map[string]string) *v1.Ingress {
+		return &v1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "shop",
+				Name:              name,
+				CreationTimestamp: metav1.Unix(created, 0),
+				Annotations:       ann,
+			},
+		}
+	}
+	named := map[string]string{annotations.LBName: "storefront"}
+
+	ing := newIngress("front", 200, named)
+	older := newIngress("old-front", 100, named)
+	newer := newIngress("new-front", 300, named)
+	grouped := newIngress("grouped", 50, map[string]string{annotations.LBName: "storefront", annotations.LBGroup: "web"})
+	other := newIngress("other", 50, map[string]string{annotations.LBName: "backoffice"})
+
+	t.Run("Name belongs to the oldest ingress", func(t *testing.T) {
+		g := NewWithT(t)
+		all := []*v1.Ingress{ing, older, newer, grouped, other}
+		g.Expect(GetLBNameOwner(ing, all)).To(Equal(older))
+		g.Expect(GetLBNameOwner(newer, all)).To(Equal(older))
+		g.Expect(GetLBNameOwner(older, all)).To(BeNil())
+	})
+
+	t.Run("Ingress without name annotation", func(t *testing.T) {
+		g := NewWithT(t)
+		g.Expect(GetLBNameOwner(newIngress("plain", 300, nil), []*v1.Ingress{older})).To(BeNil())
+	})
+}
@@ -0,0 +1,65 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"slices"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+)
+
+const (
+	// HTTPPort is a port which serves plain HTTP in vhosts with and without TLS
+	HTTPPort = 80
+	// HTTPSPort is a port which serves only HTTPS
+	HTTPSPort = 443
+)
+
+// ValidateListenPorts checks that listen ports could be used by a vhost:
+// vhost without TLS can't listen on HTTPSPort, vhost with TLS needs a port other than HTTPPort
+// to serve HTTPS and HTTPPort if HTTP is redirected to HTTPS.
+func ValidateListenPorts(ports []int32, ssl, redirect bool) error {
+	if !ssl {
+		if slices.Contains(ports, HTTPSPort) {
+			return fmt.Errorf("port %d requires TLS", HTTPSPort)
+		}
+		return nil
+	}
+
+	if !slices.ContainsFunc(ports, func(p int32) bool { return p != HTTPPort }) {
+		return fmt.Errorf("host with TLS needs a port other than %d", HTTPPort)
+	}
+	if redirect && !slices.Contains(ports, HTTPPort) {
+		return fmt.Errorf("HTTPS redirect requires port %d", HTTPPort)
+	}
+	return nil
+}
+
+// ValidateVHostPorts checks that every port of balancer is used either with or without TLS.
+// HTTPPort serves plain HTTP in all vhosts, other ports of vhosts with TLS serve HTTPS.
+func ValidateVHostPorts(vhostZones []serverscom.L7VHostZoneInput) error {
+	type portUsage struct {
+		tls    bool
+		domain string
+	}
+	// portTLS maps port to its tls usage and domain of the first vhost which uses the port
+	portTLS := make(map[int32]portUsage)
+
+	for _, vz := range vhostZones {
+		domain := ""
+		if len(vz.Domains) > 0 {
+			domain = vz.Domains[0]
+		}
+		for _, p := range vz.Ports {
+			tls := vz.SSL && p != HTTPPort
+			usage, ok := portTLS[p]
+			if !ok {
+				portTLS[p] = portUsage{tls: tls, domain: domain}
+				continue
+			}
+			if usage.tls != tls {
+				return fmt.Errorf("port %d is used with and without TLS by hosts %s and %s", p, usage.domain, domain)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+)
+
+func TestValidateListenPorts(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    []int32
+		ssl      bool
+		redirect bool
+		err      string
+	}{
+		{name: "Plain HTTP", ports: []int32{80, 8080}},
+		{name: "Plain HTTP on HTTPS port", ports: []int32{80, 443}, err: "port 443 requires TLS"},
+		{name: "TLS", ports: []int32{443, 8443}, ssl: true},
+		{name: "TLS with redirect", ports: []int32{80, 443}, ssl: true, redirect: true},
+		{name: "TLS without HTTPS port", ports: []int32{80}, ssl: true, err: "host with TLS needs a port other than 80"},
+		{name: "Redirect without HTTP port", ports: []int32{443}, ssl: true, redirect: true, err: "HTTPS redirect requires port 80"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := ValidateListenPorts(tc.ports, tc.ssl, tc.redirect)
+			if tc.err != "" {
+				g.Expect(err).To(MatchError(tc.err))
+				return
+			}
+			g.Expect(err).To(BeNil())
+		})
+	}
+}
+
+func TestValidateVHostPorts(t *testing.T) {
+	g := NewWithT(t)
+
+	vhostZones := []serverscom.L7VHostZoneInput{
+		{Domains: []string{"a.com"}, Ports: []int32{80, 443}, SSL: true},
+		{Domains: []string{"b.com"}, Ports: []int32{80, 8080}},
+		{Domains: []string{"c.com"}, Ports: []int32{80, 8443}, SSL: true},
+	}
+	g.Expect(ValidateVHostPorts(vhostZones)).To(Succeed())
+
+	vhostZones = append(vhostZones, serverscom.L7VHostZoneInput{Domains: []string{"d.com"}, Ports: []int32{8443}})
+	g.Expect(ValidateVHostPorts(vhostZones)).To(MatchError("port 8443 is used with and without TLS by hosts c.com and d.com"))
+}
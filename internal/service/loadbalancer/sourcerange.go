@@ -0,0 +1,61 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// allowAllRanges are source ranges which don't restrict clients
+var allowAllRanges = []string{"0.0.0.0/0", "::/0"}
+
+// GetSourceRanges returns client source ranges allowed to reach host. Whitelist source range annotations
+// of ingress take precedence over LoadBalancerSourceRanges of services which serve host.
+func GetSourceRanges(host string, ingressAnnotations map[string]string, services []*corev1.Service) ([]string, error) {
+	ranges, ok, err := annotations.GetWhitelistSourceRange(host, ingressAnnotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid whitelist source range: %v", err)
+	}
+	if ok {
+		return ranges, nil
+	}
+
+	for _, svc := range services {
+		for _, r := range svc.Spec.LoadBalancerSourceRanges {
+			if !slices.Contains(ranges, r) {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+	return ranges, nil
+}
+
+// IsSourceRangeRestricted returns true if source ranges don't allow all IPv4 and IPv6 clients
+func IsSourceRangeRestricted(ranges []string) bool {
+	if len(ranges) == 0 {
+		return false
+	}
+	for _, r := range allowAllRanges {
+		if !slices.Contains(ranges, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSourceRanges checks that source ranges of host could be applied by the balancer.
+// The L7 balancer has no access control, so restricted hosts can't be served.
+func ValidateSourceRanges(host string, ingressAnnotations map[string]string, services []*corev1.Service) error {
+	ranges, err := GetSourceRanges(host, ingressAnnotations, services)
+	if err != nil {
+		return err
+	}
+	if IsSourceRangeRestricted(ranges) {
+		return fmt.Errorf("source ranges %s can't be enforced by the L7 load balancer", strings.Join(ranges, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetSourceRanges(t *testing.T) {
+	services := []*corev1.Service{
+		{Spec: corev1.ServiceSpec{LoadBalancerSourceRanges: []string{"10.0.0.0/8"}}},
+		{Spec: corev1.ServiceSpec{LoadBalancerSourceRanges: []string{"10.0.0.0/8", "192.168.0.0/16"}}},
+		{},
+	}
+
+	t.Run("Service ranges are merged", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ranges, err := GetSourceRanges("example.com", nil, services)
+		g.Expect(err).To(BeNil())
+		g.Expect(ranges).To(Equal([]string{"10.0.0.0/8", "192.168.0.0/16"}))
+	})
+
+	t.Run("Annotation takes precedence", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ranges, err := GetSourceRanges("example.com", map[string]string{annotations.WhitelistSourceRange: "0.0.0.0/0,::/0"}, services)
+		g.Expect(err).To(BeNil())
+		g.Expect(ranges).To(Equal([]string{"0.0.0.0/0", "::/0"}))
+	})
+
+	t.Run("Invalid annotation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := GetSourceRanges("example.com", map[string]string{annotations.WhitelistSourceRange: "10.0.0.1"}, nil)
+		g.Expect(err).To(MatchError(`invalid whitelist source range: "10.0.0.1" is not a valid CIDR`))
+	})
+}
+
+func TestIsSourceRangeRestricted(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(IsSourceRangeRestricted(nil)).To(BeFalse())
+	g.Expect(IsSourceRangeRestricted([]string{"0.0.0.0/0", "::/0"})).To(BeFalse())
+	g.Expect(IsSourceRangeRestricted([]string{"0.0.0.0/0"})).To(BeTrue())
+	g.Expect(IsSourceRangeRestricted([]string{"10.0.0.0/8"})).To(BeTrue())
+}
@@ -0,0 +1,88 @@
+package loadbalancer
+
+import (
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+)
+
+// getUpstreams returns upstreams of path nodes with node weights multiplied by factor.
+// Drained nodes are skipped, if all nodes are drained they are kept with the default weight
+// to not leave upstream zone empty.
+func getUpstreams(p store.PathInfo, factor int) []serverscom.L7UpstreamInput {
+	var ups []serverscom.L7UpstreamInput
+	for _, ip := range p.NodeIps {
+		weight := annotations.DefaultUpstreamWeight
+		if w, ok := p.NodeWeights[ip]; ok {
+			weight = w
+		}
+		if weight == 0 {
+			continue
+		}
+		ups = append(ups, serverscom.L7UpstreamInput{
+			IP:     ip,
+			Port:   int32(p.NodePort),
+			Weight: int32(weight * factor),
+		})
+	}
+
+	if len(ups) == 0 {
+		for _, ip := range p.NodeIps {
+			ups = append(ups, serverscom.L7UpstreamInput{
+				IP:     ip,
+				Port:   int32(p.NodePort),
+				Weight: int32(annotations.DefaultUpstreamWeight * factor),
+			})
+		}
+	}
+	return ups
+}
+
+// getTotalWeight returns sum of upstreams weights
+func getTotalWeight(ups []serverscom.L7UpstreamInput) int {
+	total := 0
+	for _, u := range ups {
+		total += int(u.Weight)
+	}
+	return total
+}
+
+// reduceWeights divides weights of upstreams by their greatest common divisor
+func reduceWeights(ups []serverscom.L7UpstreamInput) []serverscom.L7UpstreamInput {
+	d := 0
+	for _, u := range ups {
+		d = gcd(d, int(u.Weight))
+	}
+	if d <= 1 {
+		return ups
+	}
+	for i := range ups {
+		ups[i].Weight /= int32(d)
+	}
+	return ups
+}
+
+// limitWeights scales weights of upstreams down proportionally if they exceed annotations.MaxUpstreamWeight,
+// every upstream keeps weight 1 at least
+func limitWeights(ups []serverscom.L7UpstreamInput) []serverscom.L7UpstreamInput {
+	var maxWeight int64
+	for _, u := range ups {
+		maxWeight = max(maxWeight, int64(u.Weight))
+	}
+	if maxWeight <= annotations.MaxUpstreamWeight {
+		return ups
+	}
+	for i := range ups {
+		w := (int64(ups[i].Weight)*annotations.MaxUpstreamWeight + maxWeight/2) / maxWeight
+		ups[i].Weight = int32(max(w, 1))
+	}
+	return reduceWeights(ups)
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
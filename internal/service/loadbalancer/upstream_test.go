@@ -0,0 +1,77 @@
+package loadbalancer
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+)
+
+func TestGetUpstreams(t *testing.T) {
+	p := store.PathInfo{
+		NodePort: 30000,
+		NodeIps:  []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+	}
+
+	t.Run("Default weights", func(t *testing.T) {
+		g := NewWithT(t)
+
+		g.Expect(reduceWeights(getUpstreams(p, 1))).To(Equal([]serverscom.L7UpstreamInput{
+			{IP: "10.0.0.1", Port: 30000, Weight: 1},
+			{IP: "10.0.0.2", Port: 30000, Weight: 1},
+			{IP: "10.0.0.3", Port: 30000, Weight: 1},
+		}))
+	})
+
+	t.Run("Node weights and drained nodes", func(t *testing.T) {
+		g := NewWithT(t)
+
+		weighted := p
+		weighted.NodeWeights = map[string]int{"10.0.0.1": 4, "10.0.0.2": 0, "10.0.0.3": 2}
+		g.Expect(reduceWeights(getUpstreams(weighted, 1))).To(Equal([]serverscom.L7UpstreamInput{
+			{IP: "10.0.0.1", Port: 30000, Weight: 2},
+			{IP: "10.0.0.3", Port: 30000, Weight: 1},
+		}))
+	})
+
+	t.Run("All nodes drained", func(t *testing.T) {
+		g := NewWithT(t)
+
+		drained := p
+		drained.NodeWeights = map[string]int{"10.0.0.1": 0, "10.0.0.2": 0, "10.0.0.3": 0}
+		g.Expect(getUpstreams(drained, 1)).To(HaveLen(3))
+	})
+
+	t.Run("Canary respects node weights", func(t *testing.T) {
+		g := NewWithT(t)
+
+		stable := p
+		stable.NodeWeights = map[string]int{"10.0.0.1": 2, "10.0.0.2": 0}
+		canary := store.PathInfo{NodePort: 30001, NodeIps: []string{"10.0.0.1"}}
+
+		// stable nodes have total weight 3, canary gets 25 percent of requests
+		ups := getCanaryUpstreams(stable, canaryPath{PathInfo: canary, weight: 25})
+		g.Expect(ups).To(Equal([]serverscom.L7UpstreamInput{
+			{IP: "10.0.0.1", Port: 30000, Weight: 2},
+			{IP: "10.0.0.3", Port: 30000, Weight: 1},
+			{IP: "10.0.0.1", Port: 30001, Weight: 1},
+		}))
+	})
+
+	t.Run("Canary weights are limited", func(t *testing.T) {
+		g := NewWithT(t)
+
+		stable := p
+		stable.NodeWeights = map[string]int{"10.0.0.1": 100, "10.0.0.2": 99, "10.0.0.3": 98}
+		canary := store.PathInfo{NodePort: 30001, NodeIps: []string{"10.0.0.1"}}
+
+		ups := getCanaryUpstreams(stable, canaryPath{PathInfo: canary, weight: 33})
+		g.Expect(ups).To(Equal([]serverscom.L7UpstreamInput{
+			{IP: "10.0.0.1", Port: 30000, Weight: 68},
+			{IP: "10.0.0.2", Port: 30000, Weight: 68},
+			{IP: "10.0.0.3", Port: 30000, Weight: 67},
+			{IP: "10.0.0.1", Port: 30001, Weight: 100},
+		}))
+	})
+}
@@ -1,19 +1,70 @@
 package loadbalancer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"regexp"
 	"strings"
 
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/networking/v1"
 )
 
 const (
 	activeStatus = "active"
+
+	// DefaultVHostZoneID is an id of vhost zone serving rules without host and default backend
+	DefaultVHostZoneID = "vhost-zone-default"
+	// DefaultVHostDomain is a catch-all domain of default vhost zone,
+	// it matches requests which don't match domains of other vhost zones
+	DefaultVHostDomain = "_"
+
+	// OwnerLabel is a label of load balancer with a name of manager resource which owns it
+	OwnerLabel = "servers.com/ingress-load-balancer"
+	// ReleasedLabel is a label of load balancer released by controller, it keeps name of the former owner
+	ReleasedLabel = "servers.com/ingress-load-balancer-released"
+	// ReclaimPolicyLabel is a label of load balancer with its reclaim policy
+	ReclaimPolicyLabel = "servers.com/ingress-reclaim-policy"
+	// AdoptedLabel is a label of load balancer which existed before controller took it over,
+	// adopted balancers are released by default after controller restarts too
+	AdoptedLabel = "servers.com/ingress-load-balancer-adopted"
+	// IngressUIDLabel is a label of standalone ingress load balancer with ingress uid,
+	// renamed balancers are found by it
+	IngressUIDLabel = "servers.com/ingress-uid"
 )
 
-// GetLoadBalancerName compose a load balancer name from ingress object
+// defaultNameRegexp matches default names of standalone ingress load balancers made of ingress uid
+var defaultNameRegexp = regexp.MustCompile(`^ingress-a[0-9a-f]{31}$`)
+
+// GetLoadBalancerName compose a load balancer name from ingress object.
+// Ingresses of the same group share a balancer named after namespace and group.
 func GetLoadBalancerName(ing *v1.Ingress) string {
-	ret := "a" + string(ing.UID)
+	if group := annotations.GetLBGroup(ing.Annotations); group != "" {
+		return groupLoadBalancerName(ing.Namespace, group)
+	}
+
+	return defaultLoadBalancerName(string(ing.UID))
+}
+
+// groupLoadBalancerName returns name of group load balancer. Namespace and group joined with a dash
+// are ambiguous ("a-b"/"c" and "a"/"b-c"), so name ends with their hash and is shortened to MaxNameLength.
+func groupLoadBalancerName(namespace, group string) string {
+	sum := sha256.Sum256([]byte(namespace + "/" + group))
+	hash := hex.EncodeToString(sum[:])[:uidHashLength]
+
+	name := fmt.Sprintf("ingress-group-%s-%s", namespace, group)
+	if maxLength := MaxNameLength - uidHashLength - 1; len(name) > maxLength {
+		name = strings.TrimRight(name[:maxLength], "-")
+	}
+	return name + "-" + hash
+}
+
+// defaultLoadBalancerName returns default name of standalone ingress load balancer made of ingress uid
+func defaultLoadBalancerName(uid string) string {
+	ret := "a" + uid
 	ret = strings.Replace(ret, "-", "", -1)
 	if len(ret) > 32 {
 		ret = ret[:32]
@@ -21,7 +72,25 @@ func GetLoadBalancerName(ing *v1.Ingress) string {
 	return fmt.Sprintf("ingress-%s", ret)
 }
 
+// IsDefaultName returns true if name is a default name of standalone ingress load balancer
+func IsDefaultName(name string) bool {
+	return defaultNameRegexp.MatchString(name)
+}
+
 // IsActiveStatus determines if lb has an active status
 func IsActiveStatus(status string) bool {
 	return strings.EqualFold(status, activeStatus)
 }
+
+// getAppProtocol returns appProtocol of service port with nodePort
+func getAppProtocol(svc *corev1.Service, nodePort int) *string {
+	if svc == nil {
+		return nil
+	}
+	for _, port := range svc.Spec.Ports {
+		if int(port.NodePort) == nodePort {
+			return port.AppProtocol
+		}
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package loadbalancer
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 	v1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -33,4 +35,26 @@ func TestGetLoadBalancerName(t *testing.T) {
 
 	nameDashes := GetLoadBalancerName(ing)
 	g.Expect(nameDashes).To(Equal(expectedNameDashes))
+
+	ing = &v1.Ingress{ObjectMeta: metav1.ObjectMeta{
+		UID:         uidWithDashes,
+		Namespace:   "default",
+		Annotations: map[string]string{annotations.LBGroup: "web"},
+	}}
+	g.Expect(GetLoadBalancerName(ing)).To(MatchRegexp(`^ingress-group-default-web-[0-9a-f]{8}$`))
+
+	// namespace and group joined with a dash are ambiguous, names differ by hash
+	other := ing.DeepCopy()
+	other.Namespace = "default-web"
+	other.Annotations[annotations.LBGroup] = "api"
+	ing.Annotations[annotations.LBGroup] = "web-api"
+	g.Expect(GetLoadBalancerName(ing)).NotTo(Equal(GetLoadBalancerName(other)))
+
+	long := ing.DeepCopy()
+	long.Namespace = strings.Repeat("n", 63)
+	g.Expect(GetLoadBalancerName(long)).To(HaveLen(MaxNameLength))
+
+	// invalid group is ignored
+	ing.Annotations[annotations.LBGroup] = "Web_Group"
+	g.Expect(GetLoadBalancerName(ing)).To(Equal(expectedNameDashes))
 }
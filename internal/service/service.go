@@ -1,21 +1,28 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/serverscom/serverscom-ingress-controller/internal/ingress"
 	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/loadbalancer"
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/sync"
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/tls"
 	"golang.org/x/net/context"
 
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+
 	v1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 )
 
@@ -30,10 +37,13 @@ type Service struct {
 	lbManager         loadbalancer.LBManagerInterface
 	store             store.Storer
 	recorder          record.EventRecorder
+	queue             workqueue.Interface
 	ingressClass      string
 	certManagerPrefix string
 	namespace         string
 	syncManager       sync.Syncer
+	// refuseConflictingRules makes rules claimed by older ingresses excluded from sync
+	refuseConflictingRules bool
 }
 
 // New creates a new Service
@@ -44,19 +54,23 @@ func New(
 	store store.Storer,
 	sync sync.Syncer,
 	recorder record.EventRecorder,
+	queue workqueue.Interface,
 	ingressClass string,
 	certManagerPrefix string,
-	namespace string) *Service {
+	namespace string,
+	refuseConflictingRules bool) *Service {
 	return &Service{
-		KubeClient:        kubeClient,
-		tlsManager:        tlsManager,
-		lbManager:         lbManager,
-		store:             store,
-		recorder:          recorder,
-		ingressClass:      ingressClass,
-		certManagerPrefix: certManagerPrefix,
-		syncManager:       sync,
-		namespace:         namespace,
+		KubeClient:             kubeClient,
+		tlsManager:             tlsManager,
+		lbManager:              lbManager,
+		store:                  store,
+		recorder:               recorder,
+		queue:                  queue,
+		ingressClass:           ingressClass,
+		certManagerPrefix:      certManagerPrefix,
+		syncManager:            sync,
+		namespace:              namespace,
+		refuseConflictingRules: refuseConflictingRules,
 	}
 }
 
@@ -85,22 +99,51 @@ func (s *Service) SyncToPortal(key string) error {
 		return nil
 	}
 
-	// get certs from ingress and sync it to portal
-	klog.V(2).Infof("start syncing tls for ingress %q", key)
-	sslCerts, err := s.syncManager.SyncTLS(ing, s.certManagerPrefix)
-	if err != nil {
-		e := fmt.Errorf("syncing tls for ingress %q failed: %v", key, err)
-		s.recorder.Eventf(ing, v1.EventTypeWarning, "Sync", e.Error())
-		return err
+	// canary ingress is served by balancers of ingresses with the same hosts and paths
+	if annotations.IsCanary(ing.Annotations) {
+		return s.syncCanary(key, ing)
 	}
 
-	// generate lb input from ingress
-	klog.V(2).Infof("start translating ingress %q to load balancer", key)
-	lbInput, err := s.lbManager.TranslateIngressToLB(ing, sslCerts)
-	if err != nil {
-		e := fmt.Errorf("translate ingress %q to LB failed: %v", key, err)
-		s.recorder.Eventf(ing, v1.EventTypeWarning, "Translate", e.Error())
-		return err
+	// ingresses of the same group are synced to one shared balancer
+	members := []*networkv1.Ingress{ing}
+	if annotations.GetLBGroup(ing.Annotations) != "" {
+		members = s.getGroupMembers(ing)
+	}
+
+	// broken members are skipped, their problems are reported on them
+	var (
+		inputs     []*serverscom.L7LoadBalancerCreateInput
+		translated []*networkv1.Ingress
+	)
+	for _, member := range members {
+		memberKey := key
+		if member != ing {
+			memberKey = member.Namespace + "/" + member.Name
+		}
+		lbInput, err := s.translateIngress(memberKey, member)
+		if err != nil {
+			if member == ing {
+				return err
+			}
+			klog.V(2).Infof("member %q of group is skipped while syncing ingress %q: %v", memberKey, key, err)
+			continue
+		}
+		inputs = append(inputs, lbInput)
+		translated = append(translated, member)
+	}
+	lbInput, conflicts := loadbalancer.MergeLoadBalancerInputs(inputs)
+	for _, c := range conflicts {
+		owner := translated[c.Owner]
+		s.recorder.Eventf(translated[c.Member], v1.EventTypeWarning, "GroupConflict",
+			"host %s: vhost settings and TLS differ from ingress %s/%s of the same group, they are ignored", c.Host, owner.Namespace, owner.Name)
+	}
+	if len(inputs) > 1 {
+		// members could use the same port with and without TLS
+		if err := loadbalancer.ValidateVHostPorts(lbInput.VHostZones); err != nil {
+			e := fmt.Errorf("merging group of ingress %q failed: %v", key, err)
+			s.recorder.Eventf(ing, v1.EventTypeWarning, "Sync", e.Error())
+			return e
+		}
 	}
 
 	klog.V(2).Infof("start syncing load balancer %q to portal", lbInput.Name)
@@ -116,7 +159,7 @@ func (s *Service) SyncToPortal(key string) error {
 		return e
 	}
 
-	// update ingress status
+	// update status of ingress and other group members
 	klog.V(2).Infof("start updating ingress %q status with load balancer IPs", key)
 	go func() {
 		ctx, cancel := context.WithTimeout(context.Background(), LBPollTimeout)
@@ -132,22 +175,248 @@ func (s *Service) SyncToPortal(key string) error {
 			ingress = append(ingress, networkv1.IngressLoadBalancerIngress{IP: ip})
 		}
 
-		ing.Status = networkv1.IngressStatus{
-			LoadBalancer: networkv1.IngressLoadBalancerStatus{
-				Ingress: ingress,
-			},
-		}
-		ingClient := s.KubeClient.NetworkingV1().Ingresses(ing.Namespace)
-		_, err = ingClient.UpdateStatus(ctx, ing, metav1.UpdateOptions{})
-		if err != nil {
-			s.recorder.Eventf(ing, v1.EventTypeWarning, "UpdateStatus", err.Error())
-			return
-		}
+		for _, member := range translated {
+			member = member.DeepCopy()
+			member.Status = networkv1.IngressStatus{
+				LoadBalancer: networkv1.IngressLoadBalancerStatus{
+					Ingress: ingress,
+				},
+			}
+			ingClient := s.KubeClient.NetworkingV1().Ingresses(member.Namespace)
+			_, err = ingClient.UpdateStatus(ctx, member, metav1.UpdateOptions{})
+			if err != nil {
+				s.recorder.Eventf(member, v1.EventTypeWarning, "UpdateStatus", err.Error())
+				continue
+			}
 
-		s.recorder.Eventf(ing, v1.EventTypeNormal, "Synced", "Successfully synced")
+			s.recorder.Eventf(member, v1.EventTypeNormal, "Synced", "Successfully synced")
+		}
 	}()
 
 	s.recorder.Eventf(ing, v1.EventTypeNormal, "Created", "Successfully created")
 
 	return nil
 }
+
+// syncCanary enqueues ingresses of the canary namespace which serve its hosts and paths,
+// canary backends are merged into their balancers on their sync
+func (s *Service) syncCanary(key string, canary *networkv1.Ingress) error {
+	s.validateAnnotations(canary)
+
+	// canary doesn't have its own balancer
+	if err := s.syncManager.CleanupLBs(s.ingressClass); err != nil {
+		s.recorder.Eventf(canary, v1.EventTypeWarning, "Sync", err.Error())
+		return err
+	}
+
+	var stables []string
+	for _, other := range s.store.GetHostPathIngresses(canary) {
+		if other.Namespace != canary.Namespace || annotations.IsCanary(other.Annotations) {
+			continue
+		}
+		stables = append(stables, other.Namespace+"/"+other.Name)
+	}
+	if len(stables) == 0 {
+		s.recorder.Eventf(canary, v1.EventTypeWarning, "Canary", "no ingress serves hosts and paths of canary ingress %q", key)
+		return nil
+	}
+
+	for _, stableKey := range stables {
+		klog.V(2).Infof("canary ingress %q changed, enqueueing ingress %q", key, stableKey)
+		s.queue.Add(stableKey)
+	}
+	return nil
+}
+
+// translateIngress reports problems of ingress, syncs its tls certs and translates it to LB input
+func (s *Service) translateIngress(key string, ing *networkv1.Ingress) (*serverscom.L7LoadBalancerCreateInput, error) {
+	// report invalid annotations of ingress and its services
+	s.validateAnnotations(ing)
+
+	// report hosts and paths claimed by older ingresses
+	translated := ing
+	if conflicts := s.store.GetHostConflicts(ing); len(conflicts) != 0 {
+		for _, c := range conflicts {
+			s.recorder.Eventf(ing, v1.EventTypeWarning, "HostConflict", c.Error())
+		}
+		if s.refuseConflictingRules {
+			translated = removeConflictingRules(ing, conflicts)
+		}
+	}
+
+	// hosts with deleted tls secrets are skipped to not serve their stale certificates
+	if missing := s.getMissingSecrets(translated); len(missing) != 0 {
+		hosts := make([]string, 0, len(missing))
+		for host := range missing {
+			hosts = append(hosts, host)
+		}
+		sort.Strings(hosts)
+		for _, host := range hosts {
+			s.recorder.Eventf(ing, v1.EventTypeWarning, "MissingSecret", "host %s: tls secret %q doesn't exist, host is skipped", host, missing[host])
+		}
+		translated = removeHosts(translated, missing)
+	}
+
+	// get certs from ingress and sync it to portal
+	klog.V(2).Infof("start syncing tls for ingress %q", key)
+	sslCerts, err := s.syncManager.SyncTLS(translated, s.certManagerPrefix)
+	if err != nil {
+		e := fmt.Errorf("syncing tls for ingress %q failed: %v", key, err)
+		s.recorder.Eventf(ing, v1.EventTypeWarning, "Sync", e.Error())
+		return nil, err
+	}
+
+	// generate lb input from ingress
+	klog.V(2).Infof("start translating ingress %q to load balancer", key)
+	lbInput, err := s.lbManager.TranslateIngressToLB(translated, sslCerts)
+	if err != nil {
+		e := fmt.Errorf("translate ingress %q to LB failed: %v", key, err)
+		s.recorder.Eventf(ing, v1.EventTypeWarning, translateErrorReason(err), e.Error())
+		return nil, err
+	}
+	s.lbManager.TrackIngress(translated)
+	return lbInput, nil
+}
+
+// getGroupMembers returns ingresses of controller class from the same namespace and group as ing,
+// ordered from the oldest one
+func (s *Service) getGroupMembers(ing *networkv1.Ingress) []*networkv1.Ingress {
+	group := annotations.GetLBGroup(ing.Annotations)
+	members := []*networkv1.Ingress{ing}
+	for _, other := range s.store.ListIngress() {
+		if other.Namespace != ing.Namespace || other.Name == ing.Name {
+			continue
+		}
+		if !ingress.IsScIngress(other, s.ingressClass) || annotations.GetLBGroup(other.Annotations) != group {
+			continue
+		}
+		members = append(members, other)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return store.IsOlderIngress(members[i], members[j])
+	})
+	return members
+}
+
+// validateAnnotations validates annotations of ingress and its backend services.
+// All errors of an object are reported in one warning event on this object.
+func (s *Service) validateAnnotations(ing *networkv1.Ingress) {
+	if err := annotations.ValidateIngressAnnotations(ing.Annotations); err != nil {
+		s.recorder.Eventf(ing, v1.EventTypeWarning, "InvalidAnnotations", err.Error())
+	}
+
+	for _, name := range ingress.GetBackendServiceNames(ing) {
+		svc, err := s.store.GetService(ing.Namespace + "/" + name)
+		if err != nil {
+			// missing services are reported on translate
+			continue
+		}
+		if err := annotations.ValidateServiceAnnotations(svc.Annotations); err != nil {
+			s.recorder.Eventf(svc, v1.EventTypeWarning, "InvalidAnnotations", err.Error())
+		}
+	}
+}
+
+// translateErrorReason returns event reason for error of translating ingress to LB
+func translateErrorReason(err error) string {
+	var (
+		serviceNotFound *store.ServiceNotFoundError
+		portNotFound    *store.PortNotFoundError
+		noNodePort      *store.NoNodePortError
+		unsupported     *store.UnsupportedBackendError
+	)
+	switch {
+	case errors.As(err, &serviceNotFound):
+		return "ServiceNotFound"
+	case errors.As(err, &portNotFound):
+		return "ServicePortNotFound"
+	case errors.As(err, &noNodePort):
+		return "ServiceNoNodePort"
+	case errors.As(err, &unsupported):
+		return "UnsupportedBackend"
+	default:
+		return "Translate"
+	}
+}
+
+// getMissingSecrets returns hosts of ingress with their tls secret keys which don't exist in store,
+// certs issued by cert manager aren't stored in secrets
+func (s *Service) getMissingSecrets(ing *networkv1.Ingress) map[string]string {
+	missing := make(map[string]string)
+	for host, secretName := range sync.MergeTLSWithAnnotations(ing) {
+		if strings.HasPrefix(secretName, s.certManagerPrefix) {
+			continue
+		}
+		sKey := ing.Namespace + "/" + secretName
+		if _, err := s.store.GetSecret(sKey); err != nil {
+			if _, ok := err.(store.NotExistsError); ok {
+				missing[host] = sKey
+			}
+		}
+	}
+	return missing
+}
+
+// removeHosts returns a copy of ingress without rules and tls settings of hosts
+func removeHosts(ing *networkv1.Ingress, hosts map[string]string) *networkv1.Ingress {
+	res := ing.DeepCopy()
+
+	var rules []networkv1.IngressRule
+	for _, rule := range res.Spec.Rules {
+		if _, ok := hosts[rule.Host]; !ok {
+			rules = append(rules, rule)
+		}
+	}
+	res.Spec.Rules = rules
+
+	var tlsList []networkv1.IngressTLS
+	for _, t := range res.Spec.TLS {
+		var tlsHosts []string
+		for _, host := range t.Hosts {
+			if _, ok := hosts[host]; !ok {
+				tlsHosts = append(tlsHosts, host)
+			}
+		}
+		if len(tlsHosts) == 0 {
+			continue
+		}
+		t.Hosts = tlsHosts
+		tlsList = append(tlsList, t)
+	}
+	res.Spec.TLS = tlsList
+
+	for host := range hosts {
+		delete(res.Annotations, annotations.LBCertificatePrefix+host)
+	}
+	return res
+}
+
+// removeConflictingRules returns a copy of ingress without paths claimed by other ingresses
+func removeConflictingRules(ing *networkv1.Ingress, conflicts []store.HostConflict) *networkv1.Ingress {
+	claimed := make(map[store.HostPath]struct{})
+	for _, c := range conflicts {
+		claimed[store.HostPath{Host: c.Host, Path: c.Path}] = struct{}{}
+	}
+
+	res := ing.DeepCopy()
+	var rules []networkv1.IngressRule
+	for _, rule := range res.Spec.Rules {
+		if rule.HTTP == nil {
+			rules = append(rules, rule)
+			continue
+		}
+		var paths []networkv1.HTTPIngressPath
+		for _, path := range rule.HTTP.Paths {
+			if _, ok := claimed[store.HostPath{Host: rule.Host, Path: path.Path}]; !ok {
+				paths = append(paths, path)
+			}
+		}
+		if len(paths) == 0 {
+			continue
+		}
+		rule.HTTP.Paths = paths
+		rules = append(rules, rule)
+	}
+	res.Spec.Rules = rules
+	return res
+}
@@ -2,11 +2,13 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
 	"github.com/serverscom/serverscom-ingress-controller/internal/mocks"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 	"golang.org/x/net/context"
 
 	. "github.com/onsi/gomega"
@@ -16,6 +18,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
 var (
@@ -46,18 +49,358 @@ var (
 	}
 )
 
+func TestSyncToPortalHostConflicts(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	lbManagerHandler := mocks.NewMockLBManagerInterface(mockCtrl)
+	lbManagerHandler.EXPECT().TrackIngress(gomock.Any()).AnyTimes()
+	syncManagerHandler := mocks.NewMockSyncer(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+
+	ing := scIngress.DeepCopy()
+	ing.Spec.Rules = []networkv1.IngressRule{
+		{
+			Host: "example.com",
+			IngressRuleValue: networkv1.IngressRuleValue{
+				HTTP: &networkv1.HTTPIngressRuleValue{
+					Paths: []networkv1.HTTPIngressPath{{Path: "/a"}, {Path: "/b"}},
+				},
+			},
+		},
+		{
+			Host: "foo.com",
+			IngressRuleValue: networkv1.IngressRuleValue{
+				HTTP: &networkv1.HTTPIngressRuleValue{
+					Paths: []networkv1.HTTPIngressPath{{Path: "/"}},
+				},
+			},
+		},
+	}
+	conflicts := []store.HostConflict{
+		{Host: "example.com", Path: "/a", Owner: "default/older"},
+		{Host: "foo.com", Path: "/", Owner: "default/older"},
+	}
+
+	for _, refuse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("Refuse conflicting rules %v", refuse), func(t *testing.T) {
+			g := NewWithT(t)
+			srv := New(fake.NewSimpleClientset(), nil, lbManagerHandler, storeHandler, syncManagerHandler, recorder, nil, scIngressClassName, scCertManagerPrefix, namespace, refuse)
+
+			storeHandler.EXPECT().GetIngress("ingress").Return(ing, nil)
+			storeHandler.EXPECT().GetHostConflicts(ing).Return(conflicts)
+			syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), gomock.Any()).Return(map[string]string{}, nil)
+			lbManagerHandler.EXPECT().TranslateIngressToLB(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(translated *networkv1.Ingress, _ map[string]string) (*serverscom.L7LoadBalancerCreateInput, error) {
+					if !refuse {
+						g.Expect(translated).To(Equal(ing))
+						return nil, errors.New("Translate error")
+					}
+					g.Expect(translated.Spec.Rules).To(HaveLen(1))
+					g.Expect(translated.Spec.Rules[0].Host).To(Equal("example.com"))
+					g.Expect(translated.Spec.Rules[0].HTTP.Paths).To(Equal([]networkv1.HTTPIngressPath{{Path: "/b"}}))
+					g.Expect(ing.Spec.Rules).To(HaveLen(2))
+					return nil, errors.New("Translate error")
+				})
+
+			err := srv.SyncToPortal("ingress")
+			g.Expect(err).To(HaveOccurred())
+
+			for _, expectedEvent := range []string{
+				`Warning HostConflict host and path "example.com/a" already claimed by ingress default/older`,
+				`Warning HostConflict host and path "foo.com/" already claimed by ingress default/older`,
+				`Warning Translate translate ingress "ingress" to LB failed: Translate error`,
+			} {
+				select {
+				case e := <-recorder.Events:
+					g.Expect(e).To(BeEquivalentTo(expectedEvent))
+				case <-time.After(time.Second * 1):
+					t.Fatal("Timeout waiting for event")
+				}
+			}
+		})
+	}
+}
+
+func TestSyncToPortalMissingSecret(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	lbManagerHandler := mocks.NewMockLBManagerInterface(mockCtrl)
+	lbManagerHandler.EXPECT().TrackIngress(gomock.Any()).AnyTimes()
+	syncManagerHandler := mocks.NewMockSyncer(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	srv := New(fake.NewSimpleClientset(), nil, lbManagerHandler, storeHandler, syncManagerHandler, recorder, nil, scIngressClassName, scCertManagerPrefix, namespace, false)
+
+	ing := scIngress.DeepCopy()
+	ing.Namespace = namespace
+	ing.Spec.Rules = []networkv1.IngressRule{{Host: "example.com"}, {Host: "foo.com"}, {Host: "bar.com"}}
+	ing.Spec.TLS = []networkv1.IngressTLS{
+		{Hosts: []string{"example.com", "foo.com"}, SecretName: "deleted"},
+		{Hosts: []string{"bar.com"}, SecretName: scCertManagerPrefix + "cert-id"},
+	}
+
+	// the secret was deleted, its hosts are skipped instead of keeping stale certificates
+	storeHandler.EXPECT().GetIngress("default/ingress").Return(ing, nil)
+	storeHandler.EXPECT().GetHostConflicts(ing).Return(nil)
+	storeHandler.EXPECT().GetSecret("default/deleted").Return(nil, store.NotExistsError("default/deleted")).Times(2)
+	syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), scCertManagerPrefix).DoAndReturn(
+		func(translated *networkv1.Ingress, _ string) (map[string]string, error) {
+			g.Expect(translated.Spec.Rules).To(Equal([]networkv1.IngressRule{{Host: "bar.com"}}))
+			g.Expect(translated.Spec.TLS).To(Equal([]networkv1.IngressTLS{
+				{Hosts: []string{"bar.com"}, SecretName: scCertManagerPrefix + "cert-id"},
+			}))
+			return map[string]string{"bar.com": "cert-id"}, nil
+		})
+	lbManagerHandler.EXPECT().TranslateIngressToLB(gomock.Any(), map[string]string{"bar.com": "cert-id"}).Return(nil, errors.New("Translate error"))
+
+	err := srv.SyncToPortal("default/ingress")
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(ing.Spec.Rules).To(HaveLen(3))
+
+	for _, expectedEvent := range []string{
+		`Warning MissingSecret host example.com: tls secret "default/deleted" doesn't exist, host is skipped`,
+		`Warning MissingSecret host foo.com: tls secret "default/deleted" doesn't exist, host is skipped`,
+		`Warning Translate translate ingress "default/ingress" to LB failed: Translate error`,
+	} {
+		g.Expect(recorder.Events).To(Receive(Equal(expectedEvent)))
+	}
+}
+
+func TestSyncToPortalGroup(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	lbManagerHandler := mocks.NewMockLBManagerInterface(mockCtrl)
+	lbManagerHandler.EXPECT().TrackIngress(gomock.Any()).AnyTimes()
+	syncManagerHandler := mocks.NewMockSyncer(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	fakeClient := fake.NewSimpleClientset()
+	srv := New(fakeClient, nil, lbManagerHandler, storeHandler, syncManagerHandler, recorder, nil, scIngressClassName, scCertManagerPrefix, namespace, false)
+
+	now := time.Now()
+	newMember := func(name, group string, created time.Time) *networkv1.Ingress {
+		ing := scIngress.DeepCopy()
+		ing.Name = name
+		ing.Namespace = namespace
+		ing.CreationTimestamp = metav1.NewTime(created)
+		ing.Annotations = map[string]string{annotations.LBGroup: group}
+		return ing
+	}
+	older := newMember("older", "web", now.Add(-time.Hour))
+	newer := newMember("newer", "web", now)
+	otherGroup := newMember("other-group", "api", now.Add(-2*time.Hour))
+	otherNamespace := newMember("other-namespace", "web", now.Add(-2*time.Hour))
+	otherNamespace.Namespace = "other"
+	for _, ing := range []*networkv1.Ingress{older, newer} {
+		_, err := fakeClient.NetworkingV1().Ingresses(namespace).Create(context.Background(), ing, metav1.CreateOptions{})
+		g.Expect(err).To(BeNil())
+	}
+
+	inputs := map[string]*serverscom.L7LoadBalancerCreateInput{
+		"older": {
+			Name: "ingress-group-default-web",
+			VHostZones: []serverscom.L7VHostZoneInput{
+				{ID: "vhost-zone-a.com", LocationZones: []serverscom.L7LocationZoneInput{{Location: "/", UpstreamID: "upstream-zone-a-30000"}}},
+			},
+			UpstreamZones: []serverscom.L7UpstreamZoneInput{{ID: "upstream-zone-a-30000"}},
+		},
+		"newer": {
+			Name: "ingress-group-default-web",
+			VHostZones: []serverscom.L7VHostZoneInput{
+				{ID: "vhost-zone-b.com", LocationZones: []serverscom.L7LocationZoneInput{{Location: "/", UpstreamID: "upstream-zone-b-30001"}}},
+			},
+			UpstreamZones: []serverscom.L7UpstreamZoneInput{{ID: "upstream-zone-b-30001"}},
+		},
+	}
+
+	storeHandler.EXPECT().GetIngress("default/newer").Return(newer, nil)
+	storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{newer, otherGroup, older, otherNamespace})
+	storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil).Times(2)
+	syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), gomock.Any()).Return(map[string]string{}, nil).Times(2)
+	var translated []string
+	lbManagerHandler.EXPECT().TranslateIngressToLB(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ing *networkv1.Ingress, _ map[string]string) (*serverscom.L7LoadBalancerCreateInput, error) {
+			translated = append(translated, ing.Name)
+			return inputs[ing.Name], nil
+		}).Times(2)
+	syncManagerHandler.EXPECT().SyncL7LB(gomock.Any()).DoAndReturn(
+		func(lbInput *serverscom.L7LoadBalancerCreateInput) (*serverscom.L7LoadBalancer, error) {
+			g.Expect(lbInput.Name).To(Equal("ingress-group-default-web"))
+			g.Expect(lbInput.VHostZones).To(HaveLen(2))
+			g.Expect(lbInput.UpstreamZones).To(HaveLen(2))
+			return inProcessLB, nil
+		})
+	syncManagerHandler.EXPECT().SyncStatus(gomock.Any(), inProcessLB).Return(activeLB, nil)
+
+	err := srv.SyncToPortal("default/newer")
+	g.Expect(err).To(BeNil())
+	g.Expect(translated).To(Equal([]string{"older", "newer"}))
+
+	// status of all members is updated with group balancer IPs
+	g.Eventually(func(g Gomega) {
+		for _, name := range []string{"older", "newer"} {
+			ing, err := fakeClient.NetworkingV1().Ingresses(namespace).Get(context.Background(), name, metav1.GetOptions{})
+			g.Expect(err).To(BeNil())
+			g.Expect(ing.Status.LoadBalancer.Ingress).To(Equal([]networkv1.IngressLoadBalancerIngress{{IP: "1.2.3.4"}}))
+		}
+	}).Should(Succeed())
+}
+
+func TestSyncToPortalGroupProblems(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	lbManagerHandler := mocks.NewMockLBManagerInterface(mockCtrl)
+	lbManagerHandler.EXPECT().TrackIngress(gomock.Any()).AnyTimes()
+	syncManagerHandler := mocks.NewMockSyncer(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	fakeClient := fake.NewSimpleClientset()
+	srv := New(fakeClient, nil, lbManagerHandler, storeHandler, syncManagerHandler, recorder, nil, scIngressClassName, scCertManagerPrefix, namespace, false)
+
+	now := time.Now()
+	newMember := func(name string, created time.Time) *networkv1.Ingress {
+		ing := scIngress.DeepCopy()
+		ing.Name = name
+		ing.Namespace = namespace
+		ing.CreationTimestamp = metav1.NewTime(created)
+		ing.Annotations = map[string]string{annotations.LBGroup: "web"}
+		return ing
+	}
+	older := newMember("older", now.Add(-2*time.Hour))
+	broken := newMember("broken", now.Add(-time.Hour))
+	newer := newMember("newer", now)
+
+	newInput := func(ssl bool, upstreamID string) *serverscom.L7LoadBalancerCreateInput {
+		return &serverscom.L7LoadBalancerCreateInput{
+			Name: "group",
+			VHostZones: []serverscom.L7VHostZoneInput{{
+				ID:            "vhost-zone-a.com",
+				Domains:       []string{"a.com"},
+				SSL:           ssl,
+				LocationZones: []serverscom.L7LocationZoneInput{{Location: "/", UpstreamID: upstreamID}},
+			}},
+			UpstreamZones: []serverscom.L7UpstreamZoneInput{{ID: upstreamID}},
+		}
+	}
+	inputs := map[string]*serverscom.L7LoadBalancerCreateInput{
+		"older": newInput(false, "upstream-zone-a-30000"),
+		"newer": newInput(true, "upstream-zone-b-30001"),
+	}
+
+	storeHandler.EXPECT().GetIngress("default/newer").Return(newer, nil)
+	storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{newer, broken, older})
+	storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil).Times(3)
+	syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), gomock.Any()).Return(map[string]string{}, nil).Times(3)
+	lbManagerHandler.EXPECT().TranslateIngressToLB(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ing *networkv1.Ingress, _ map[string]string) (*serverscom.L7LoadBalancerCreateInput, error) {
+			if ing.Name == "broken" {
+				return nil, errors.New("vhost or upstream can't be empty")
+			}
+			return inputs[ing.Name], nil
+		}).Times(3)
+	syncManagerHandler.EXPECT().SyncL7LB(gomock.Any()).DoAndReturn(
+		func(lbInput *serverscom.L7LoadBalancerCreateInput) (*serverscom.L7LoadBalancer, error) {
+			g.Expect(lbInput.VHostZones).To(HaveLen(1))
+			g.Expect(lbInput.VHostZones[0].SSL).To(BeFalse())
+			g.Expect(lbInput.UpstreamZones).To(HaveLen(2))
+			return activeLB, nil
+		})
+	syncManagerHandler.EXPECT().SyncStatus(gomock.Any(), activeLB).Return(activeLB, nil).AnyTimes()
+
+	err := srv.SyncToPortal("default/newer")
+	g.Expect(err).To(BeNil())
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	g.Expect(events).To(ContainElements(
+		`Warning Translate translate ingress "default/broken" to LB failed: vhost or upstream can't be empty`,
+		"Warning GroupConflict host a.com: vhost settings and TLS differ from ingress default/older of the same group, they are ignored",
+	))
+}
+
+func TestSyncToPortalCanary(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	lbManagerHandler := mocks.NewMockLBManagerInterface(mockCtrl)
+	lbManagerHandler.EXPECT().TrackIngress(gomock.Any()).AnyTimes()
+	syncManagerHandler := mocks.NewMockSyncer(mockCtrl)
+	recorder := record.NewFakeRecorder(10)
+	fakeClient := fake.NewSimpleClientset()
+	queue := workqueue.New()
+	defer queue.ShutDown()
+	srv := New(fakeClient, nil, lbManagerHandler, storeHandler, syncManagerHandler, recorder, queue, scIngressClassName, scCertManagerPrefix, namespace, false)
+
+	newIngress := func(name, ns string, canary bool) *networkv1.Ingress {
+		ing := scIngress.DeepCopy()
+		ing.Name = name
+		ing.Namespace = ns
+		if canary {
+			ing.Annotations = map[string]string{annotations.Canary: "true", annotations.CanaryWeight: "10"}
+		}
+		return ing
+	}
+	canary := newIngress("canary", namespace, true)
+	stable := newIngress("stable", namespace, false)
+	otherCanary := newIngress("other-canary", namespace, true)
+	otherNamespace := newIngress("stable", "other", false)
+	_, err := fakeClient.NetworkingV1().Ingresses(namespace).Create(context.Background(), stable, metav1.CreateOptions{})
+	NewWithT(t).Expect(err).To(BeNil())
+
+	t.Run("Ingresses served by canary are enqueued", func(t *testing.T) {
+		g := NewWithT(t)
+
+		storeHandler.EXPECT().GetIngress("default/canary").Return(canary, nil)
+		syncManagerHandler.EXPECT().CleanupLBs(scIngressClassName).Return(nil)
+		storeHandler.EXPECT().GetHostPathIngresses(canary).Return([]*networkv1.Ingress{otherNamespace, otherCanary, stable})
+
+		err := srv.SyncToPortal("default/canary")
+		g.Expect(err).To(BeNil())
+		g.Expect(queue.Len()).To(Equal(1))
+		key, _ := queue.Get()
+		g.Expect(key).To(Equal("default/stable"))
+		queue.Done(key)
+	})
+
+	t.Run("Canary without stable ingress", func(t *testing.T) {
+		g := NewWithT(t)
+
+		storeHandler.EXPECT().GetIngress("default/canary").Return(canary, nil)
+		syncManagerHandler.EXPECT().CleanupLBs(scIngressClassName).Return(nil)
+		storeHandler.EXPECT().GetHostPathIngresses(canary).Return([]*networkv1.Ingress{otherNamespace, otherCanary})
+
+		err := srv.SyncToPortal("default/canary")
+		g.Expect(err).To(BeNil())
+		g.Expect(recorder.Events).To(Receive(Equal(`Warning Canary no ingress serves hosts and paths of canary ingress "default/canary"`)))
+	})
+}
+
 func TestSyncToPortal(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	storeHandler := mocks.NewMockStorer(mockCtrl)
 	lbManagerHandler := mocks.NewMockLBManagerInterface(mockCtrl)
+	lbManagerHandler.EXPECT().TrackIngress(gomock.Any()).AnyTimes()
 	tlsManagerHandler := mocks.NewMockTLSManagerInterface(mockCtrl)
 	syncManagerHandler := mocks.NewMockSyncer(mockCtrl)
 	recorder := record.NewFakeRecorder(10)
 	fakeClient := fake.NewSimpleClientset()
 
-	srv := New(fakeClient, tlsManagerHandler, lbManagerHandler, storeHandler, syncManagerHandler, recorder, scIngressClassName, scCertManagerPrefix, namespace)
+	srv := New(fakeClient, tlsManagerHandler, lbManagerHandler, storeHandler, syncManagerHandler, recorder, nil, scIngressClassName, scCertManagerPrefix, namespace, false)
+	storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil).AnyTimes()
+
 	t.Run("Ingress does not exist", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -114,6 +457,31 @@ func TestSyncToPortal(t *testing.T) {
 		}
 	})
 
+	t.Run("Invalid annotations", func(t *testing.T) {
+		g := NewWithT(t)
+
+		invalidIngress := scIngress.DeepCopy()
+		invalidIngress.Annotations = map[string]string{
+			annotations.LBGeoIPEnabled:  "invalid",
+			annotations.LBMinTLSVersion: "TLSv9",
+		}
+		storeHandler.EXPECT().GetIngress("ingress").Return(invalidIngress, nil)
+		syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), gomock.Any()).Return(nil, errors.New("TLS sync error"))
+
+		err := srv.SyncToPortal("ingress")
+		g.Expect(err).To(HaveOccurred())
+
+		select {
+		case e := <-recorder.Events:
+			g.Expect(e).To(HavePrefix("Warning InvalidAnnotations ["))
+			g.Expect(e).To(ContainSubstring(annotations.LBGeoIPEnabled))
+			g.Expect(e).To(ContainSubstring(annotations.LBMinTLSVersion))
+		case <-time.After(time.Second * 1):
+			t.Fatal("Timeout waiting for event")
+		}
+		<-recorder.Events
+	})
+
 	t.Run("Error translating Ingress to LB", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -133,6 +501,33 @@ func TestSyncToPortal(t *testing.T) {
 		}
 	})
 
+	t.Run("Error translating Ingress with missing backend", func(t *testing.T) {
+		g := NewWithT(t)
+
+		errs := map[string]error{
+			"ServiceNotFound":     &store.ServiceNotFoundError{Key: "default/svc", Err: store.NotExistsError("default/svc")},
+			"ServicePortNotFound": &store.PortNotFoundError{Service: "svc", Port: networkv1.ServiceBackendPort{Name: "http"}},
+			"ServiceNoNodePort":   &store.NoNodePortError{Service: "svc"},
+			"UnsupportedBackend":  &store.UnsupportedBackendError{Host: "example.com", Path: "/"},
+		}
+		for reason, translateErr := range errs {
+			storeHandler.EXPECT().GetIngress("ingress").Return(scIngress, nil)
+			syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), gomock.Any()).Return(map[string]string{}, nil)
+			lbManagerHandler.EXPECT().TranslateIngressToLB(gomock.Any(), gomock.Any()).Return(nil, translateErr)
+
+			err := srv.SyncToPortal("ingress")
+			g.Expect(err).To(HaveOccurred())
+
+			select {
+			case e := <-recorder.Events:
+				expectedEvent := fmt.Sprintf(`Warning %s translate ingress "ingress" to LB failed: %s`, reason, translateErr.Error())
+				g.Expect(e).To(BeEquivalentTo(expectedEvent))
+			case <-time.After(time.Second * 1):
+				t.Fatal("Timeout waiting for event")
+			}
+		}
+	})
+
 	t.Run("Error syncing L7 LB", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -186,11 +581,13 @@ func TestSyncToPortal(t *testing.T) {
 	t.Run("Successful sync", func(t *testing.T) {
 		g := NewWithT(t)
 
-		_, err := fakeClient.NetworkingV1().Ingresses(namespace).Create(context.Background(), scIngress, metav1.CreateOptions{})
+		syncedIngress := scIngress.DeepCopy()
+		syncedIngress.Namespace = namespace
+		_, err := fakeClient.NetworkingV1().Ingresses(namespace).Create(context.Background(), syncedIngress, metav1.CreateOptions{})
 		g.Expect(err).To(BeNil())
 
 		lbInput := new(serverscom.L7LoadBalancerCreateInput)
-		storeHandler.EXPECT().GetIngress("ingress").Return(scIngress, nil)
+		storeHandler.EXPECT().GetIngress("ingress").Return(syncedIngress, nil)
 		syncManagerHandler.EXPECT().SyncTLS(gomock.Any(), gomock.Any()).Return(map[string]string{}, nil)
 		lbManagerHandler.EXPECT().TranslateIngressToLB(gomock.Any(), gomock.Any()).Return(lbInput, nil)
 		syncManagerHandler.EXPECT().SyncL7LB(gomock.Any()).Return(inProcessLB, nil)
@@ -7,6 +7,7 @@ import (
 
 	serverscom "github.com/serverscom/serverscom-go-client/pkg"
 	"github.com/serverscom/serverscom-ingress-controller/internal/ingress"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 	"github.com/serverscom/serverscom-ingress-controller/internal/service/loadbalancer"
 	"k8s.io/klog/v2"
 )
@@ -18,20 +19,7 @@ const (
 // SyncL7LB add or update L7 Load Balancer in portal
 func (s *SyncManager) SyncL7LB(lb *serverscom.L7LoadBalancerCreateInput) (*serverscom.L7LoadBalancer, error) {
 	if s.lbMgr.HasRegistration(lb.Name) {
-		lbUpdateInput := &serverscom.L7LoadBalancerUpdateInput{
-			Name:              lb.Name,
-			StoreLogs:         lb.StoreLogs,
-			StoreLogsRegionID: lb.StoreLogsRegionID,
-			Geoip:             lb.Geoip,
-			VHostZones:        lb.VHostZones,
-			UpstreamZones:     lb.UpstreamZones,
-			ClusterID:         lb.ClusterID,
-		}
-		if lbUpdateInput.ClusterID == nil {
-			lbUpdateInput.SharedCluster = new(bool)
-			*lbUpdateInput.SharedCluster = true
-		}
-		result, err, _ := s.lbMgr.UpdateLoadBalancer(lbUpdateInput)
+		result, err, _ := s.lbMgr.UpdateLoadBalancer(loadbalancer.NewUpdateInput(lb))
 		return result, err
 	} else {
 		result, err, _ := s.lbMgr.NewLoadBalancer(lb)
@@ -46,9 +34,11 @@ func (s *SyncManager) CleanupLBs(ingressClass string) error {
 	// LB is valid if it has corresponding SC Ingress
 	validLBs := make(map[string]struct{})
 	for _, ing := range allIngresses {
-		if ingress.IsScIngress(ing, ingressClass) {
-			lbName := loadbalancer.GetLoadBalancerName(ing)
-			validLBs[lbName] = struct{}{}
+		// canary ingresses are served by balancers of other ingresses
+		if ingress.IsScIngress(ing, ingressClass) && !annotations.IsCanary(ing.Annotations) {
+			for _, lbName := range s.lbMgr.LoadBalancerNames(ing) {
+				validLBs[lbName] = struct{}{}
+			}
 		}
 	}
 
@@ -12,6 +12,8 @@ import (
 	. "github.com/onsi/gomega"
 	serverscom "github.com/serverscom/serverscom-go-client/pkg"
 	"github.com/serverscom/serverscom-ingress-controller/internal/mocks"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/loadbalancer"
 	"go.uber.org/mock/gomock"
 	networkv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -74,6 +76,16 @@ func TestCleanupLBs(t *testing.T) {
 
 	syncManager := New(nil, lbManagerHandler, storeHandler, nil)
 
+	lbManagerHandler.EXPECT().
+		LoadBalancerNames(gomock.Any()).
+		DoAndReturn(func(ing *networkv1.Ingress) []string {
+			if ing.Name == "renamed-ingress" {
+				return []string{"shop", "ingress-a999"}
+			}
+			return []string{loadbalancer.GetLoadBalancerName(ing)}
+		}).
+		AnyTimes()
+
 	scClass := "serverscom"
 	otherClass := "default"
 	allIngresses := []*networkv1.Ingress{
@@ -107,6 +119,50 @@ func TestCleanupLBs(t *testing.T) {
 		g.Expect(err).To(BeNil())
 	})
 
+	t.Run("Group LB is kept while it has members", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		member := &networkv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:         "789",
+				Name:        "member",
+				Namespace:   "default",
+				Annotations: map[string]string{annotations.LBGroup: "web"},
+			},
+			Spec: networkv1.IngressSpec{
+				IngressClassName: &scClass,
+			},
+		}
+		removed := member.DeepCopy()
+		removed.Annotations[annotations.LBGroup] = "api"
+		storeHandler.EXPECT().ListIngress().Return(append(allIngresses, member))
+		lbManagerHandler.EXPECT().GetIds().Return([]string{
+			loadbalancer.GetLoadBalancerName(member), loadbalancer.GetLoadBalancerName(removed), validLBId,
+		})
+		lbManagerHandler.EXPECT().DeleteLoadBalancer(loadbalancer.GetLoadBalancerName(removed)).Return(nil)
+
+		err := syncManager.CleanupLBs(scClass)
+		g.Expect(err).To(BeNil())
+	})
+
+	t.Run("LB being renamed is kept", func(t *testing.T) {
+		g := NewGomegaWithT(t)
+		renamed := &networkv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:  "999",
+				Name: "renamed-ingress",
+			},
+			Spec: networkv1.IngressSpec{
+				IngressClassName: &scClass,
+			},
+		}
+		storeHandler.EXPECT().ListIngress().Return(append(allIngresses, renamed))
+		lbManagerHandler.EXPECT().GetIds().Return([]string{"ingress-a999", "invalid-id", validLBId})
+		lbManagerHandler.EXPECT().DeleteLoadBalancer("invalid-id").Return(nil)
+
+		err := syncManager.CleanupLBs(scClass)
+		g.Expect(err).To(BeNil())
+	})
+
 	t.Run("Fail to delete LB", func(t *testing.T) {
 		g := NewGomegaWithT(t)
 		storeHandler.EXPECT().ListIngress().Return(allIngresses)
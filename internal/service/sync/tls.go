@@ -4,13 +4,14 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
 	tlsmanager "github.com/serverscom/serverscom-ingress-controller/internal/service/tls"
 	v1 "k8s.io/api/core/v1"
 	networkv1 "k8s.io/api/networking/v1"
 )
 
 const (
-	TLS_ANNOTATION_PREFIX = "servers.com/certificate-"
+	TLS_ANNOTATION_PREFIX = annotations.LBCertificatePrefix
 )
 
 // SyncTLS syncs ingress tls certs stored in secrets to portal.
@@ -21,7 +22,7 @@ const (
 func (s *SyncManager) SyncTLS(ingress *networkv1.Ingress, certManagerPrefix string) (map[string]string, error) {
 	var sslCerts = make(map[string]string)
 
-	hostsSecrets := mergeTLSWithAnnotations(ingress)
+	hostsSecrets := MergeTLSWithAnnotations(ingress)
 	for host, secretName := range hostsSecrets {
 		if strings.HasPrefix(secretName, certManagerPrefix) {
 			id := strings.TrimPrefix(secretName, certManagerPrefix)
@@ -37,19 +38,11 @@ func (s *SyncManager) SyncTLS(ingress *networkv1.Ingress, certManagerPrefix stri
 		if err != nil {
 			return nil, fmt.Errorf("fetching secret with key %q from store failed: %v", sKey, err)
 		}
-		cert, ok := secret.Data[v1.TLSCertKey]
-		if !ok {
-			return nil, fmt.Errorf("secret %q has no 'tls.crt'", sKey)
-		}
-
-		key, ok := secret.Data[v1.TLSPrivateKeyKey]
-		if !ok {
-			return nil, fmt.Errorf("secret %q has no 'tls.key'", sKey)
-		}
-
-		if err := tlsmanager.ValidateCertificate(cert); err != nil {
-			return nil, fmt.Errorf("secret %q has invalid 'tls.crt': %v", sKey, err)
+		if err := tlsmanager.ValidateTLSSecret(sKey, secret); err != nil {
+			return nil, err
 		}
+		cert := secret.Data[v1.TLSCertKey]
+		key := secret.Data[v1.TLSPrivateKeyKey]
 
 		primary, chain := tlsmanager.SplitCerts(cert)
 
@@ -84,9 +77,9 @@ func (s *SyncManager) SyncTLS(ingress *networkv1.Ingress, certManagerPrefix stri
 	return sslCerts, nil
 }
 
-// mergeTLSWithAnnotations merge info about host and associated secret from ingress.Spec.TLS and ingress.Annotations
+// MergeTLSWithAnnotations merge info about host and associated secret from ingress.Spec.TLS and ingress.Annotations
 // returns map[host]secret
-func mergeTLSWithAnnotations(ingress *networkv1.Ingress) map[string]string {
+func MergeTLSWithAnnotations(ingress *networkv1.Ingress) map[string]string {
 	res := make(map[string]string)
 
 	for _, tls := range ingress.Spec.TLS {
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	corev1 "k8s.io/api/core/v1"
 )
 
 // GetPemFingerprint returns sha1 fingerprint from cert
@@ -50,6 +52,24 @@ func ValidateCertificate(crt []byte) error {
 	return nil
 }
 
+// ValidateTLSSecret validates that secret has tls key and valid tls cert
+func ValidateTLSSecret(key string, secret *corev1.Secret) error {
+	cert, ok := secret.Data[corev1.TLSCertKey]
+	if !ok {
+		return fmt.Errorf("secret %q has no 'tls.crt'", key)
+	}
+
+	if _, ok := secret.Data[corev1.TLSPrivateKeyKey]; !ok {
+		return fmt.Errorf("secret %q has no 'tls.key'", key)
+	}
+
+	if err := ValidateCertificate(cert); err != nil {
+		return fmt.Errorf("secret %q has invalid 'tls.crt': %v", key, err)
+	}
+
+	return nil
+}
+
 // FindCertificate finds DER block from cert
 func FindCertificate(crt []byte) []byte {
 	certDERBlock, _ := pem.Decode(crt)
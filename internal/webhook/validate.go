@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	serverscom "github.com/serverscom/serverscom-go-client/pkg"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/loadbalancer"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/sync"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/tls"
+
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// ValidateIngress runs sync checks for an ingress of controller class.
+// Objects used by ingress could be created after it, e.g. tls secrets by cert-manager,
+// so their problems are returned as warnings.
+// Returns warnings and an aggregated error with all problems of ingress or nil.
+func (s *Server) ValidateIngress(ing *networkv1.Ingress) ([]string, error) {
+	if !ingress.IsScIngress(ing, s.ingressClass) {
+		return nil, nil
+	}
+
+	var (
+		warnings []string
+		errs     []error
+	)
+
+	if err := annotations.ValidateIngressAnnotations(ing.Annotations); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, err := s.store.GetIngressHostsInfo(ing); err != nil {
+		var serviceNotFound *store.ServiceNotFoundError
+		if errors.As(err, &serviceNotFound) {
+			warnings = append(warnings, err.Error())
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, name := range ingress.GetBackendServiceNames(ing) {
+		svc, err := s.store.GetService(ing.Namespace + "/" + name)
+		if err != nil {
+			// missing service is already reported by hosts info
+			continue
+		}
+		if err := annotations.ValidateServiceAnnotations(svc.Annotations); err != nil {
+			errs = append(errs, fmt.Errorf("service %s: %v", name, err))
+		}
+		warnings = append(warnings, serviceWarnings(svc)...)
+	}
+
+	errs = append(errs, validateIngressPaths(ing)...)
+	errs = append(errs, validateListenPorts(ing, s.sslRedirect)...)
+	errs = append(errs, s.validateSourceRanges(ing)...)
+	errs = append(errs, s.validateHostConflicts(ing)...)
+	errs = append(errs, s.validateLBName(ing)...)
+	warnings = append(warnings, s.validateIngressSecrets(ing)...)
+
+	return warnings, utilerrors.NewAggregate(errs)
+}
+
+// ValidateService runs sync checks for a service used by ingresses of controller class.
+// Returns warnings and an aggregated error with all found problems or nil.
+func (s *Server) ValidateService(svc *corev1.Service) ([]string, error) {
+	var errs []error
+
+	ingresses := s.getServiceIngresses(svc)
+	if len(ingresses) == 0 {
+		return nil, nil
+	}
+
+	if err := annotations.ValidateServiceAnnotations(svc.Annotations); err != nil {
+		errs = append(errs, err)
+	}
+
+	for _, ing := range ingresses {
+		for _, rule := range ing.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.Service == nil || path.Backend.Service.Name != svc.Name {
+					continue
+				}
+				if _, err := store.GetServiceNodePort(svc, path.Backend.Service.Port); err != nil {
+					errs = append(errs, fmt.Errorf("used by ingress %s: %v", ing.Name, err))
+				}
+			}
+		}
+	}
+
+	return serviceWarnings(svc), utilerrors.NewAggregate(errs)
+}
+
+// serviceWarnings returns warnings about valid service annotations and port app protocols
+// which can't be applied to the balancer and are ignored
+func serviceWarnings(svc *corev1.Service) []string {
+	var warnings []string
+	for _, k := range annotations.GetUnsupportedAnnotations(svc.Annotations) {
+		warnings = append(warnings, fmt.Sprintf("service %s: %s isn't supported by the L7 load balancer, annotation is ignored", svc.Name, k))
+	}
+	for _, port := range svc.Spec.Ports {
+		if err := annotations.CheckAppProtocol(svc.Annotations, port.AppProtocol); err != nil {
+			warnings = append(warnings, fmt.Sprintf("service %s: port %d: %v", svc.Name, port.Port, err))
+		}
+	}
+	return warnings
+}
+
+// validateIngressSecrets checks that all tls secrets of ingress exist and valid, returns warnings
+func (s *Server) validateIngressSecrets(ing *networkv1.Ingress) []string {
+	var warnings []string
+
+	hostsSecrets := sync.MergeTLSWithAnnotations(ing)
+	hosts := make([]string, 0, len(hostsSecrets))
+	for host := range hostsSecrets {
+		hosts = append(hosts, host)
+	}
+	slices.Sort(hosts)
+
+	for _, host := range hosts {
+		secretName := hostsSecrets[host]
+		if strings.HasPrefix(secretName, s.certManagerPrefix) {
+			continue
+		}
+		sKey := ing.Namespace + "/" + secretName
+		secret, err := s.store.GetSecret(sKey)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("host %s: fetching secret %q failed: %v", host, sKey, err))
+			continue
+		}
+		if err := tls.ValidateTLSSecret(sKey, secret); err != nil {
+			warnings = append(warnings, fmt.Sprintf("host %s: %v", host, err))
+		}
+	}
+
+	return warnings
+}
+
+// validateHostConflicts checks that host and path of ingress rules
+// aren't claimed by older ingresses of controller class
+func (s *Server) validateHostConflicts(ing *networkv1.Ingress) []error {
+	var errs []error
+	for _, c := range s.store.GetHostConflicts(ing) {
+		errs = append(errs, c)
+	}
+	return errs
+}
+
+// validateLBName checks that load balancer name annotation of ingress
+// isn't used by older ingresses of controller class
+func (s *Server) validateLBName(ing *networkv1.Ingress) []error {
+	name := annotations.GetLBName(ing.Annotations)
+	if name == "" {
+		return nil
+	}
+
+	var ingresses []*networkv1.Ingress
+	for _, other := range s.store.ListIngress() {
+		if ingress.IsScIngress(other, s.ingressClass) {
+			ingresses = append(ingresses, other)
+		}
+	}
+	if owner := loadbalancer.GetLBNameOwner(ing, ingresses); owner != nil {
+		return []error{fmt.Errorf("load balancer name %q is used by ingress %s/%s", name, owner.Namespace, owner.Name)}
+	}
+	return nil
+}
+
+// validateIngressPaths checks that paths of ingress rules could be expressed as locations
+func validateIngressPaths(ing *networkv1.Ingress) []error {
+	var errs []error
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if _, err := loadbalancer.GetPathLocations(path.Path, path.PathType); err != nil {
+				errs = append(errs, fmt.Errorf("host %s: %v", rule.Host, err))
+			}
+		}
+	}
+	return errs
+}
+
+// validateListenPorts checks that listen ports of ingress hosts could be used with their TLS settings
+func validateListenPorts(ing *networkv1.Ingress, sslRedirect bool) []error {
+	var errs []error
+
+	tlsHosts := sync.MergeTLSWithAnnotations(ing)
+	redirect := annotations.GetSSLRedirect(ing.Annotations, sslRedirect)
+
+	var vhostZones []serverscom.L7VHostZoneInput
+	seen := make(map[string]bool)
+	for _, rule := range ing.Spec.Rules {
+		if rule.Host == "" || seen[rule.Host] {
+			continue
+		}
+		seen[rule.Host] = true
+
+		_, ssl := tlsHosts[rule.Host]
+		ports := annotations.GetListenPorts(rule.Host, ing.Annotations)
+		if ports == nil {
+			ports = []int32{loadbalancer.HTTPPort}
+			if ssl {
+				ports = append(ports, loadbalancer.HTTPSPort)
+			}
+		}
+		if err := loadbalancer.ValidateListenPorts(ports, ssl, ssl && redirect); err != nil {
+			errs = append(errs, fmt.Errorf("host %s: %v", rule.Host, err))
+			continue
+		}
+		vhostZones = append(vhostZones, serverscom.L7VHostZoneInput{
+			Domains: []string{rule.Host},
+			Ports:   ports,
+			SSL:     ssl,
+		})
+	}
+
+	if err := loadbalancer.ValidateVHostPorts(vhostZones); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// validateSourceRanges checks that hosts of ingress aren't restricted to source ranges
+// which can't be enforced by the balancer
+func (s *Server) validateSourceRanges(ing *networkv1.Ingress) []error {
+	var errs []error
+
+	var hosts []string
+	hostServices := make(map[string][]*corev1.Service)
+	for _, rule := range ing.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		if _, ok := hostServices[rule.Host]; !ok {
+			hosts = append(hosts, rule.Host)
+			hostServices[rule.Host] = nil
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil {
+				continue
+			}
+			svc, err := s.store.GetService(ing.Namespace + "/" + path.Backend.Service.Name)
+			if err != nil {
+				// missing service is already reported by hosts info
+				continue
+			}
+			hostServices[rule.Host] = append(hostServices[rule.Host], svc)
+		}
+	}
+
+	for _, host := range hosts {
+		if err := loadbalancer.ValidateSourceRanges(host, ing.Annotations, hostServices[host]); err != nil {
+			errs = append(errs, fmt.Errorf("host %s: %v", host, err))
+		}
+	}
+	return errs
+}
+
+// getServiceIngresses returns ingresses of controller class which use service as backend
+func (s *Server) getServiceIngresses(svc *corev1.Service) []*networkv1.Ingress {
+	var res []*networkv1.Ingress
+	for _, ing := range s.store.ListIngress() {
+		if ing.Namespace != svc.Namespace || !ingress.IsScIngress(ing, s.ingressClass) {
+			continue
+		}
+		if slices.Contains(ingress.GetBackendServiceNames(ing), svc.Name) {
+			res = append(res, ing)
+		}
+	}
+	return res
+}
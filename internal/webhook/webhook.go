@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	ValidatePath   = "/validate"
+	HealthzPath    = "/healthz"
+	maxRequestSize = 3 * 1024 * 1024
+	shutdownPeriod = 5 * time.Second
+)
+
+// Server represents a validating admission webhook server.
+// It runs the same checks as sync to portal does and rejects invalid objects.
+type Server struct {
+	store             store.Storer
+	ingressClass      string
+	certManagerPrefix string
+	// sslRedirect is a controller default of HTTP to HTTPS redirect for TLS hosts
+	sslRedirect bool
+}
+
+// New creates a new webhook server
+func New(store store.Storer, ingressClass, certManagerPrefix string, sslRedirect bool) *Server {
+	return &Server{
+		store:             store,
+		ingressClass:      ingressClass,
+		certManagerPrefix: certManagerPrefix,
+		sslRedirect:       sslRedirect,
+	}
+}
+
+// Run runs HTTPS server on addr until stopCh is closed
+func (s *Server) Run(addr, certFile, keyFile string, stopCh chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(ValidatePath, s)
+	mux.HandleFunc(HealthzPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownPeriod)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			klog.Errorf("webhook server shutdown failed: %v", err)
+		}
+	}()
+
+	klog.Infof("starting admission webhook server on %s", addr)
+	if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// ServeHTTP handles AdmissionReview requests
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("can't read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	review := &admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(body, review); err != nil {
+		http.Error(w, fmt.Sprintf("can't decode admission review: %v", err), http.StatusBadRequest)
+		return
+	}
+	if review.Request == nil {
+		http.Error(w, "admission review has no request", http.StatusBadRequest)
+		return
+	}
+
+	review.Response = s.review(review.Request)
+	review.Response.UID = review.Request.UID
+	review.Request = nil
+
+	resp, err := json.Marshal(review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("can't encode admission review: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(resp); err != nil {
+		klog.Errorf("can't write admission review response: %v", err)
+	}
+}
+
+// review validates object from admission request
+func (s *Server) review(req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return allowed()
+	}
+
+	var (
+		warnings []string
+		err      error
+	)
+	switch req.Kind.Kind {
+	case "Ingress":
+		ing := &networkv1.Ingress{}
+		if err := json.Unmarshal(req.Object.Raw, ing); err != nil {
+			return denied(fmt.Errorf("can't decode ingress: %v", err))
+		}
+		if ing.Namespace == "" {
+			ing.Namespace = req.Namespace
+		}
+		warnings, err = s.ValidateIngress(ing)
+	case "Service":
+		svc := &corev1.Service{}
+		if err := json.Unmarshal(req.Object.Raw, svc); err != nil {
+			return denied(fmt.Errorf("can't decode service: %v", err))
+		}
+		if svc.Namespace == "" {
+			svc.Namespace = req.Namespace
+		}
+		warnings, err = s.ValidateService(svc)
+	default:
+		return allowed()
+	}
+
+	var resp *admissionv1.AdmissionResponse
+	if err != nil {
+		klog.V(2).Infof("rejecting %s %s/%s: %v", req.Kind.Kind, req.Namespace, req.Name, err)
+		resp = denied(err)
+	} else {
+		resp = allowed()
+	}
+	resp.Warnings = warnings
+	return resp
+}
+
+// allowed returns allowed admission response
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// denied returns denied admission response with error message
+func denied(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+			Message: err.Error(),
+		},
+	}
+}
@@ -0,0 +1,337 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/serverscom/serverscom-ingress-controller/internal/ingress/controller/store"
+	"github.com/serverscom/serverscom-ingress-controller/internal/mocks"
+	"github.com/serverscom/serverscom-ingress-controller/internal/service/annotations"
+	"github.com/serverscom/serverscom-ingress-controller/internal/testdata"
+	"go.uber.org/mock/gomock"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var (
+	scIngressClassName  = "serverscom"
+	scCertManagerPrefix = "sc-certmgr-cert-id-"
+)
+
+func newIngress(name, host, path, serviceName string) *networkv1.Ingress {
+	return &networkv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: networkv1.IngressSpec{
+			IngressClassName: &scIngressClassName,
+			Rules: []networkv1.IngressRule{
+				{
+					Host: host,
+					IngressRuleValue: networkv1.IngressRuleValue{
+						HTTP: &networkv1.HTTPIngressRuleValue{
+							Paths: []networkv1.HTTPIngressPath{
+								{
+									Path: path,
+									Backend: networkv1.IngressBackend{
+										Service: &networkv1.IngressServiceBackend{
+											Name: serviceName,
+											Port: networkv1.ServiceBackendPort{Number: 80},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func newService(name string, nodePort int32) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{{Port: 80, NodePort: nodePort}},
+		},
+	}
+}
+
+func doReview(t *testing.T, s *Server, kind string, obj interface{}) *admissionv1.AdmissionResponse {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	review := admissionv1.AdmissionReview{
+		TypeMeta: metav1.TypeMeta{APIVersion: "admission.k8s.io/v1", Kind: "AdmissionReview"},
+		Request: &admissionv1.AdmissionRequest{
+			UID:       types.UID("review-uid"),
+			Kind:      metav1.GroupVersionKind{Kind: kind},
+			Namespace: "default",
+			Operation: admissionv1.Create,
+			Object:    runtime.RawExtension{Raw: raw},
+		},
+	}
+	body, err := json.Marshal(review)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status code %d: %s", rec.Code, rec.Body.String())
+	}
+
+	result := admissionv1.AdmissionReview{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Response == nil || result.Response.UID != "review-uid" {
+		t.Fatalf("unexpected response: %s", rec.Body.String())
+	}
+	return result.Response
+}
+
+func TestServeHTTP(t *testing.T) {
+	g := NewWithT(t)
+	s := New(nil, scIngressClassName, scCertManagerPrefix, true)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ValidatePath, nil))
+	g.Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader([]byte("{"))))
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, ValidatePath, bytes.NewReader([]byte("{}"))))
+	g.Expect(rec.Code).To(Equal(http.StatusBadRequest))
+}
+
+func TestValidateIngress(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	s := New(storeHandler, scIngressClassName, scCertManagerPrefix, true)
+
+	t.Run("Ingress of other class", func(t *testing.T) {
+		g := NewWithT(t)
+		ing := newIngress("test-ingress", "example.com", "/", "test-service")
+		otherClass := "other"
+		ing.Spec.IngressClassName = &otherClass
+
+		resp := doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeTrue())
+	})
+
+	t.Run("Valid ingress", func(t *testing.T) {
+		g := NewWithT(t)
+		ing := newIngress("test-ingress", "example.com", "/", "test-service")
+		ing.Spec.TLS = []networkv1.IngressTLS{
+			{Hosts: []string{"example.com"}, SecretName: "test-secret"},
+			{Hosts: []string{"foo.com"}, SecretName: scCertManagerPrefix + "123"},
+		}
+		secret := &corev1.Secret{
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       []byte(testdata.ValidPEM),
+				corev1.TLSPrivateKeyKey: []byte("valid-key"),
+			},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(gomock.Any()).Return(map[string]store.HostInfo{}, nil)
+		storeHandler.EXPECT().GetService("default/test-service").Return(newService("test-service", 30000), nil).Times(2)
+		storeHandler.EXPECT().GetSecret("default/test-secret").Return(secret, nil)
+		storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil)
+
+		resp := doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeTrue())
+	})
+
+	t.Run("Invalid ingress", func(t *testing.T) {
+		g := NewWithT(t)
+		ing := newIngress("test-ingress", "example.com", "/", "test-service")
+		ing.Annotations = map[string]string{
+			annotations.LBGeoIPEnabled:       "invalid",
+			annotations.ListenPorts:          "80",
+			annotations.WhitelistSourceRange: "10.0.0.0/8",
+		}
+		ing.Spec.TLS = []networkv1.IngressTLS{
+			{Hosts: []string{"example.com"}, SecretName: "test-secret"},
+		}
+		svc := newService("test-service", 30000)
+		svc.Annotations = map[string]string{annotations.LBBalancingAlgorithm: "random"}
+		invalidPath := ing.Spec.Rules[0].HTTP.Paths[0]
+		invalidPath.Path = "/foo bar"
+		ing.Spec.Rules[0].HTTP.Paths = append(ing.Spec.Rules[0].HTTP.Paths, invalidPath)
+
+		storeHandler.EXPECT().GetIngressHostsInfo(gomock.Any()).Return(nil, errors.New("service test-service: port 80 not found"))
+		storeHandler.EXPECT().GetService("default/test-service").Return(svc, nil).Times(3)
+		storeHandler.EXPECT().GetSecret("default/test-secret").Return(nil, store.NotExistsError("default/test-secret"))
+		storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return([]store.HostConflict{
+			{Host: "example.com", Path: "/", Owner: "default/other-ingress"},
+		})
+
+		resp := doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeFalse())
+		g.Expect(resp.Result.Message).To(ContainSubstring(annotations.LBGeoIPEnabled))
+		g.Expect(resp.Result.Message).To(ContainSubstring("service test-service: port 80 not found"))
+		g.Expect(resp.Result.Message).To(ContainSubstring("service test-service: metadata.annotations[" + annotations.LBBalancingAlgorithm + "]"))
+		g.Expect(resp.Result.Message).To(ContainSubstring(`host example.com: path "/foo bar" contains characters which can't be used in a location`))
+		g.Expect(resp.Result.Message).To(ContainSubstring(`host example.com: host with TLS needs a port other than 80`))
+		g.Expect(resp.Result.Message).To(ContainSubstring(`host example.com: source ranges 10.0.0.0/8 can't be enforced by the L7 load balancer`))
+		g.Expect(resp.Result.Message).To(ContainSubstring(`host and path "example.com/" already claimed by ingress default/other-ingress`))
+		g.Expect(resp.Warnings).To(ConsistOf(`host example.com: fetching secret "default/test-secret" failed: no object matching key "default/test-secret" in local store`))
+	})
+
+	t.Run("Missing service and secret are warnings", func(t *testing.T) {
+		g := NewWithT(t)
+		ing := newIngress("test-ingress", "example.com", "/", "test-service")
+		ing.Spec.TLS = []networkv1.IngressTLS{
+			{Hosts: []string{"example.com"}, SecretName: "test-secret"},
+		}
+
+		notFound := &store.ServiceNotFoundError{Key: "default/test-service", Err: store.NotExistsError("default/test-service")}
+		storeHandler.EXPECT().GetIngressHostsInfo(gomock.Any()).Return(nil, notFound)
+		storeHandler.EXPECT().GetService("default/test-service").Return(nil, store.NotExistsError("default/test-service")).Times(2)
+		storeHandler.EXPECT().GetSecret("default/test-secret").Return(nil, store.NotExistsError("default/test-secret"))
+		storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil)
+
+		resp := doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeTrue())
+		g.Expect(resp.Warnings).To(ConsistOf(
+			notFound.Error(),
+			`host example.com: fetching secret "default/test-secret" failed: no object matching key "default/test-secret" in local store`,
+		))
+	})
+
+	t.Run("Controller redirect default requires port 80", func(t *testing.T) {
+		g := NewWithT(t)
+		ing := newIngress("test-ingress", "example.com", "/", "test-service")
+		ing.Annotations = map[string]string{annotations.ListenPorts: "443"}
+		ing.Spec.TLS = []networkv1.IngressTLS{
+			{Hosts: []string{"example.com"}, SecretName: scCertManagerPrefix + "123"},
+		}
+		storeHandler.EXPECT().GetIngressHostsInfo(gomock.Any()).Return(map[string]store.HostInfo{}, nil)
+		storeHandler.EXPECT().GetService("default/test-service").Return(newService("test-service", 30000), nil).Times(2)
+		storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil).Times(2)
+
+		resp := doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeFalse())
+		g.Expect(resp.Result.Message).To(ContainSubstring("host example.com: HTTPS redirect requires port 80"))
+
+		ing.Annotations[annotations.SSLRedirect] = "false"
+		storeHandler.EXPECT().GetIngressHostsInfo(gomock.Any()).Return(map[string]store.HostInfo{}, nil)
+		storeHandler.EXPECT().GetService("default/test-service").Return(newService("test-service", 30000), nil).Times(2)
+
+		resp = doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeTrue())
+	})
+
+	t.Run("Load balancer name used by older ingress", func(t *testing.T) {
+		g := NewWithT(t)
+		ing := newIngress("test-ingress", "example.com", "/", "test-service")
+		ing.CreationTimestamp = metav1.Unix(200, 0)
+		ing.Annotations = map[string]string{annotations.LBName: "storefront"}
+		older := newIngress("older-ingress", "foo.com", "/", "test-service")
+		older.CreationTimestamp = metav1.Unix(100, 0)
+		older.Annotations = map[string]string{annotations.LBName: "storefront"}
+
+		storeHandler.EXPECT().GetIngressHostsInfo(gomock.Any()).Return(map[string]store.HostInfo{}, nil)
+		storeHandler.EXPECT().GetService("default/test-service").Return(newService("test-service", 30000), nil).Times(2)
+		storeHandler.EXPECT().GetHostConflicts(gomock.Any()).Return(nil)
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing, older})
+
+		resp := doReview(t, s, "Ingress", ing)
+		g.Expect(resp.Allowed).To(BeFalse())
+		g.Expect(resp.Result.Message).To(ContainSubstring(`load balancer name "storefront" is used by ingress default/older-ingress`))
+	})
+}
+
+func TestValidateService(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	storeHandler := mocks.NewMockStorer(mockCtrl)
+	s := New(storeHandler, scIngressClassName, scCertManagerPrefix, true)
+
+	ing := newIngress("test-ingress", "example.com", "/", "test-service")
+
+	t.Run("Service isn't used by ingresses", func(t *testing.T) {
+		g := NewWithT(t)
+		svc := newService("unused-service", 0)
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing})
+
+		resp := doReview(t, s, "Service", svc)
+		g.Expect(resp.Allowed).To(BeTrue())
+	})
+
+	t.Run("Valid service", func(t *testing.T) {
+		g := NewWithT(t)
+		svc := newService("test-service", 30000)
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing})
+
+		resp := doReview(t, s, "Service", svc)
+		g.Expect(resp.Allowed).To(BeTrue())
+	})
+
+	t.Run("Service without NodePort", func(t *testing.T) {
+		g := NewWithT(t)
+		svc := newService("test-service", 0)
+		svc.Annotations = map[string]string{annotations.AppHealthcheckInterval: "0"}
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing})
+
+		resp := doReview(t, s, "Service", svc)
+		g.Expect(resp.Allowed).To(BeFalse())
+		g.Expect(resp.Result.Message).To(ContainSubstring("used by ingress test-ingress: service test-service has no NodePort"))
+		g.Expect(resp.Result.Message).To(ContainSubstring(annotations.AppHealthcheckInterval))
+	})
+
+	t.Run("Unsupported annotations are warnings", func(t *testing.T) {
+		g := NewWithT(t)
+		svc := newService("test-service", 30000)
+		svc.Annotations = map[string]string{annotations.ProxyReadTimeout: "10m"}
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing})
+
+		resp := doReview(t, s, "Service", svc)
+		g.Expect(resp.Allowed).To(BeTrue())
+		g.Expect(resp.Warnings).To(ConsistOf("service test-service: servers.com/proxy-read-timeout isn't supported by the L7 load balancer, annotation is ignored"))
+	})
+
+	t.Run("H2C app protocol is a warning", func(t *testing.T) {
+		g := NewWithT(t)
+		svc := newService("test-service", 30000)
+		svc.Spec.Ports[0].AppProtocol = &[]string{"kubernetes.io/h2c"}[0]
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing})
+
+		resp := doReview(t, s, "Service", svc)
+		g.Expect(resp.Allowed).To(BeTrue())
+		g.Expect(resp.Warnings).To(ConsistOf("service test-service: port 80: appProtocol kubernetes.io/h2c isn't supported, the balancer speaks HTTP/2 to upstreams only as gRPC transport, HTTP is used"))
+	})
+
+	t.Run("Invalid timeout is rejected", func(t *testing.T) {
+		g := NewWithT(t)
+		svc := newService("test-service", 30000)
+		svc.Annotations = map[string]string{annotations.ProxyReadTimeout: "10"}
+		storeHandler.EXPECT().ListIngress().Return([]*networkv1.Ingress{ing})
+
+		resp := doReview(t, s, "Service", svc)
+		g.Expect(resp.Allowed).To(BeFalse())
+		g.Expect(resp.Result.Message).To(ContainSubstring("must be a duration with units"))
+	})
+}